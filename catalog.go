@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Catalog caches the schemas, tables, columns and functions of a database
+// for completion.
+type Catalog struct {
+	schemas   []string
+	tables    map[string][]string
+	columns   map[string][]Column
+	functions []string
+}
+
+var builtinFunctions = []string{
+	"abs", "array_agg", "avg", "ceil", "coalesce", "concat", "count",
+	"current_setting", "date_part", "date_trunc", "dense_rank", "extract",
+	"first_value", "floor", "generate_series", "greatest", "jsonb_agg",
+	"jsonb_build_object", "json_agg", "json_build_object", "lag", "last_value",
+	"lead", "least", "length", "lower", "max", "min", "now", "nullif", "random",
+	"rank", "regexp_replace", "replace", "round", "row_number", "split_part",
+	"string_agg", "substring", "sum", "to_char", "to_date", "to_timestamp",
+	"trim", "upper",
+}
+
+func catalogKey(schema string, table string) string {
+	return schema + "." + table
+}
+
+// LoadCatalog reads the catalog through the connection type when it has its
+// own, otherwise from its tables and their columns.
+func (params Connection) LoadCatalog() (Catalog, error) {
+	session, err := params.OpenSession()
+
+	if err != nil {
+		return Catalog{}, err
+	}
+	defer session.Close()
+
+	if source, ok := session.conn.(catalogSource); ok {
+		return source.Catalog()
+	}
+
+	tables, err := session.conn.Tables()
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	schema := "main"
+	catalog := Catalog{
+		schemas:   []string{schema},
+		tables:    map[string][]string{schema: tables},
+		columns:   map[string][]Column{},
+		functions: builtinFunctions,
+	}
+
+	for _, table := range tables {
+		columns, err := session.conn.Columns(table)
+		if err != nil {
+			return Catalog{}, err
+		}
+		catalog.columns[catalogKey(schema, table)] = columns
+	}
+
+	return catalog, nil
+}
+
+func (c *postgresConn) Catalog() (Catalog, error) {
+	conn := c.conn
+
+	catalog := Catalog{
+		tables:  map[string][]string{},
+		columns: map[string][]Column{},
+	}
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT schema_name FROM information_schema.schemata
+		WHERE schema_name NOT LIKE 'pg\_%' AND schema_name <> 'information_schema'
+		ORDER BY schema_name`)
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	catalog.schemas, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	rows, err = conn.Query(context.Background(),
+		`SELECT table_schema, table_name, column_name, data_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY table_schema, table_name, ordinal_position`)
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	for rows.Next() {
+		var schema, table string
+		var column Column
+		err = rows.Scan(&schema, &table, &column.Name, &column.DataType, &column.Nullable)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		key := catalogKey(schema, table)
+		if _, ok := catalog.columns[key]; !ok {
+			catalog.tables[schema] = append(catalog.tables[schema], table)
+		}
+		catalog.columns[key] = append(catalog.columns[key], column)
+	}
+	if err = rows.Err(); err != nil {
+		return Catalog{}, err
+	}
+
+	rows, err = conn.Query(context.Background(),
+		`SELECT DISTINCT routine_name FROM information_schema.routines
+		WHERE routine_schema NOT IN ('pg_catalog', 'information_schema')`)
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	catalog.functions, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return Catalog{}, err
+	}
+	catalog.functions = append(catalog.functions, builtinFunctions...)
+	sort.Strings(catalog.functions)
+
+	return catalog, nil
+}
+
+// tableColumns finds the columns of a table referenced with an optional
+// schema, searching the public schema first.
+func (c Catalog) tableColumns(schema string, table string) []Column {
+	if schema != "" {
+		return c.columns[catalogKey(schema, table)]
+	}
+
+	if columns, ok := c.columns[catalogKey("public", table)]; ok {
+		return columns
+	}
+
+	for _, s := range c.schemas {
+		if columns, ok := c.columns[catalogKey(s, table)]; ok {
+			return columns
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Exit codes of the command line
+const (
+	EXIT_OK    = 0
+	EXIT_ERROR = 1
+	EXIT_USAGE = 2
+)
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = ""
+
+// configErr holds the problems with the config file, shown when the
+// interface starts and printed before the other commands run.
+var configErr error
+
+const usage = `termtable - Terminal based database client
+
+Usage:
+  termtable                        open the welcome menu
+  termtable connect <name>         open a saved connection
+  termtable --url <url>            open a connection without saving it
+  termtable query [flags] <name>   run SQL without the interface
+  termtable view <file>            browse a csv or json file, - for stdin
+  termtable list                   print the saved connections
+  termtable add [flags] <name>     save a connection
+  termtable remove <name>          delete a saved connection
+
+Flags:
+  --url string    postgres://, mysql://, sqlite: or duckdb: URL, or "host=... dbname=..." settings
+  -h, --help      show this help
+  --version       print the version
+
+Run "termtable <command> --help" for the flags of a command.
+`
+
+func versionString() string {
+	if version != "" {
+		return version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	return "(devel)"
+}
+
+// newFlagSet returns a flag set that leaves printing help to the caller.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {}
+	return fs
+}
+
+// parseArgs parses the flags of fs wherever they appear between the
+// positional arguments, which are returned in order.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagError turns a parse error into an exit code, printing the help.
+func flagError(err error, help func(w io.Writer)) int {
+	if errors.Is(err, flag.ErrHelp) {
+		help(os.Stdout)
+		return EXIT_OK
+	}
+
+	help(os.Stderr)
+	return EXIT_USAGE
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, usage)
+}
+
+// runCLI runs the command in args and returns the exit code.
+func runCLI(args []string) int {
+	fs := newFlagSet("termtable")
+	url := fs.String("url", "", "")
+	showVersion := fs.Bool("version", false, "")
+
+	if err := fs.Parse(args); err != nil {
+		return flagError(err, printUsage)
+	}
+
+	if *showVersion {
+		fmt.Println("termtable", versionString())
+		return EXIT_OK
+	}
+
+	args = fs.Args()
+	configErr = loadConfig()
+
+	if *url != "" {
+		if len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "termtable: --url does not take a command")
+			return EXIT_USAGE
+		}
+		return connectURL(*url)
+	}
+
+	if len(args) == 0 {
+		return runTUI(defaultModel())
+	}
+
+	if command := args[0]; configErr != nil && command != "connect" {
+		for _, line := range strings.Split(configErr.Error(), "\n") {
+			fmt.Fprintln(os.Stderr, "termtable: config:", line)
+		}
+	}
+
+	switch command, args := args[0], args[1:]; command {
+	case "connect":
+		return connectCommand(args)
+	case "query":
+		return queryCommand(args)
+	case "view":
+		return viewCommand(args)
+	case "list":
+		return listCommand(args)
+	case "add":
+		return addCommand(args)
+	case "remove":
+		return removeCommand(args)
+	case "help":
+		printUsage(os.Stdout)
+		return EXIT_OK
+	default:
+		fmt.Fprintf(os.Stderr, "termtable: unknown command %q\n\n", command)
+		printUsage(os.Stderr)
+		return EXIT_USAGE
+	}
+}
+
+func runTUI(m model) int {
+	m.startupErr = errors.Join(configErr, m.startupErr)
+
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error running program:", err)
+		return EXIT_ERROR
+	}
+
+	return EXIT_OK
+}
+
+// defaultModel opens the default connection of the config file, or the
+// welcome menu when there is none.
+func defaultModel() model {
+	if cfg.DefaultConnection == "" {
+		return newModel()
+	}
+
+	conn, err := LoadConnection(cfg.DefaultConnection)
+	if err != nil {
+		m := newModel()
+		m.startupErr = fmt.Errorf("default_connection: %w", err)
+		return m
+	}
+
+	m := newModel().withConnection(conn)
+	if err := MarkConnectionUsed(&conn); err != nil {
+		m.startupErr = fmt.Errorf("could not record last use: %w", err)
+	}
+
+	return m
+}
+
+// withDefaults fills in the host and port the client libraries would assume.
+func (params Connection) withDefaults() Connection {
+	port, ok := defaultPorts[params.Kind()]
+	if !ok {
+		return params
+	}
+
+	if params.Host == "" {
+		params.Host = "localhost"
+	}
+	if params.Port == "" {
+		params.Port = port
+	}
+
+	return params
+}
+
+// connectURL opens a session for a connection string without saving it.
+func connectURL(s string) int {
+	conn, err := ParseConnectionString(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable: --url:", err)
+		return EXIT_USAGE
+	}
+
+	conn = conn.withDefaults()
+	conn.Name = conn.Target()
+
+	return runTUI(newModel().withConnection(conn))
+}
+
+func connectCommand(args []string) int {
+	fs := newFlagSet("connect")
+	help := func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: termtable connect <name>")
+	}
+
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return flagError(err, help)
+	}
+	if len(names) != 1 {
+		help(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	conn, err := LoadConnection(names[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	if err := MarkConnectionUsed(&conn); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable: could not record last use:", err)
+	}
+
+	return runTUI(newModel().withConnection(conn))
+}
+
+func listCommand(args []string) int {
+	fs := newFlagSet("list")
+	help := func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: termtable list")
+	}
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return flagError(err, help)
+	}
+	if len(rest) != 0 {
+		help(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	conns, err := ListConnections()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tTARGET\tENV\tGROUP\tLAST USED")
+
+	for _, conn := range conns {
+		lastUsed := "never"
+		if !conn.LastUsed.IsZero() {
+			lastUsed = timeAgo(conn.LastUsed)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			conn.Name, conn.Kind(), conn.Target(), conn.Env, conn.Group, lastUsed)
+	}
+
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	return EXIT_OK
+}
+
+func addCommand(args []string) int {
+	fs := newFlagSet("add")
+	url := fs.String("url", "", "postgres://, mysql://, sqlite: or duckdb: URL, or \"host=... dbname=...\" settings (required)")
+	env := fs.String("env", "", "environment such as prod, staging or dev")
+	color := fs.String("color", "", "colour of the connection, overrides the environment")
+	group := fs.String("group", "", "group to list the connection under")
+	readOnly := fs.Bool("read-only", false, "open sessions read only")
+	pgpass := fs.Bool("pgpass", false, "take the password from pgpass instead of the keyring")
+	force := fs.Bool("force", false, "replace a saved connection with the same name")
+
+	help := func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: termtable add --url <url> [flags] <name>")
+		fmt.Fprintln(w)
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+		fs.SetOutput(os.Stderr)
+	}
+
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return flagError(err, help)
+	}
+	if len(names) != 1 || *url == "" {
+		help(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	conn, err := ParseConnectionString(*url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable: --url:", err)
+		return EXIT_USAGE
+	}
+
+	conn = conn.withDefaults()
+	conn.Name = names[0]
+	conn.Env = *env
+	conn.Color = *color
+	conn.Group = *group
+	conn.ReadOnly = conn.ReadOnly || *readOnly
+	conn.PgpassLinked = *pgpass
+
+	if err := conn.validateColor(); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable: --color:", err)
+		return EXIT_USAGE
+	}
+	if err := conn.validateSSL(); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	if !*force {
+		if exists, err := connectionExists(conn.Name); err != nil {
+			fmt.Fprintln(os.Stderr, "termtable:", err)
+			return EXIT_ERROR
+		} else if exists {
+			fmt.Fprintf(os.Stderr, "termtable: a connection named %q is already saved, use --force to replace it\n", conn.Name)
+			return EXIT_ERROR
+		}
+	}
+
+	if err := SaveConnection(conn); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	fmt.Printf("Saved %s\n", conn.Name)
+	return EXIT_OK
+}
+
+func removeCommand(args []string) int {
+	fs := newFlagSet("remove")
+	help := func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: termtable remove <name>")
+	}
+
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return flagError(err, help)
+	}
+	if len(names) != 1 {
+		help(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	exists, err := connectionExists(names[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+	if !exists {
+		fmt.Fprintf(os.Stderr, "termtable: no saved connection named %q\n", names[0])
+		return EXIT_ERROR
+	}
+
+	if err := DeleteConnection(names[0]); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	fmt.Printf("Removed %s\n", names[0])
+	return EXIT_OK
+}
+
+func connectionExists(name string) (bool, error) {
+	conns, err := ListConnections()
+	if err != nil {
+		return false, err
+	}
+
+	for _, conn := range conns {
+		if conn.Name == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const maxCompletions = 8
+
+type CompletionKind string
+
+const (
+	COMPLETE_COLUMN   CompletionKind = "column"
+	COMPLETE_ALIAS    CompletionKind = "alias"
+	COMPLETE_TABLE    CompletionKind = "table"
+	COMPLETE_SCHEMA   CompletionKind = "schema"
+	COMPLETE_FUNCTION CompletionKind = "function"
+	COMPLETE_KEYWORD  CompletionKind = "keyword"
+)
+
+// Candidates are listed in this order when they match the same prefix
+var completionOrder = map[CompletionKind]int{
+	COMPLETE_COLUMN:   0,
+	COMPLETE_ALIAS:    1,
+	COMPLETE_TABLE:    2,
+	COMPLETE_SCHEMA:   3,
+	COMPLETE_FUNCTION: 4,
+	COMPLETE_KEYWORD:  5,
+}
+
+type completion struct {
+	text   string
+	kind   CompletionKind
+	detail string
+}
+
+type tableRef struct {
+	schema string
+	table  string
+	alias  string
+}
+
+// significantTokens drops whitespace and comments.
+func significantTokens(sql string) []sqlToken {
+	var tokens []sqlToken
+	for _, token := range tokenizeSQL(sql) {
+		if token.kind != TOKEN_WHITESPACE && token.kind != TOKEN_COMMENT {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+func unquoteIdent(token sqlToken) string {
+	if token.kind == TOKEN_QUOTED_IDENT {
+		quote := token.text[:1]
+		return strings.ReplaceAll(strings.Trim(token.text, quote), quote+quote, quote)
+	}
+	return token.text
+}
+
+// referencedTables finds the tables and their aliases used after FROM, JOIN,
+// UPDATE and INTO.
+func referencedTables(sql string) []tableRef {
+	var refs []tableRef
+	tokens := significantTokens(sql)
+
+	isName := func(i int) bool {
+		return i < len(tokens) && (tokens[i].kind == TOKEN_QUOTED_IDENT ||
+			(tokens[i].kind == TOKEN_WORD && !isSQLKeyword(tokens[i].text)))
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		keyword := strings.ToUpper(tokens[i].text)
+		if tokens[i].kind != TOKEN_WORD || (keyword != "FROM" && keyword != "JOIN" && keyword != "UPDATE" && keyword != "INTO") {
+			continue
+		}
+
+		for j := i + 1; isName(j); {
+			ref := tableRef{table: unquoteIdent(tokens[j])}
+			j++
+
+			if j+1 < len(tokens) && tokens[j].text == "." && isName(j+1) {
+				ref.schema = ref.table
+				ref.table = unquoteIdent(tokens[j+1])
+				j += 2
+			}
+
+			if j < len(tokens) && strings.EqualFold(tokens[j].text, "AS") {
+				j++
+			}
+
+			if isName(j) {
+				ref.alias = unquoteIdent(tokens[j])
+				j++
+			}
+
+			refs = append(refs, ref)
+
+			if keyword != "FROM" || j >= len(tokens) || tokens[j].text != "," {
+				break
+			}
+			j++
+		}
+	}
+
+	return refs
+}
+
+// wordBeforeCursor returns the identifier being typed at the cursor, split
+// into an optional qualifier before the last dot and the prefix after it.
+func wordBeforeCursor(line []rune, col int) (string, string) {
+	start := col
+	for start > 0 {
+		r := line[start-1]
+		if !(isIdentChar(r) || r == '.' || r == '"') {
+			break
+		}
+		start--
+	}
+
+	word := string(line[start:col])
+	if i := strings.LastIndex(word, "."); i >= 0 {
+		return strings.Trim(word[:i], `"`), strings.Trim(word[i+1:], `"`)
+	}
+
+	return "", strings.Trim(word, `"`)
+}
+
+// previousKeyword returns the last word before the identifier being typed.
+func previousKeyword(sql string) string {
+	tokens := significantTokens(sql)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].kind == TOKEN_WORD {
+			if i == len(tokens)-1 && !unicode.IsSpace(rune(sql[len(sql)-1])) {
+				continue
+			}
+			return strings.ToUpper(tokens[i].text)
+		}
+		if tokens[i].text != "." {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// completeSQL lists the candidates for the identifier ending at the cursor
+// given as a line and column of the statement.
+func completeSQL(catalog Catalog, sql string, row int, col int) (string, []completion) {
+	lines := strings.Split(sql, "\n")
+	if row >= len(lines) {
+		return "", nil
+	}
+
+	line := []rune(lines[row])
+	col = min(col, len(line))
+	qualifier, prefix := wordBeforeCursor(line, col)
+
+	if qualifier == "" && prefix == "" {
+		return "", nil
+	}
+
+	before := strings.Join(append(lines[:row], string(line[:col])), "\n")
+	refs := referencedTables(sql)
+
+	var candidates []completion
+	add := func(text string, kind CompletionKind, detail string) {
+		if strings.HasPrefix(strings.ToLower(text), strings.ToLower(prefix)) && !strings.EqualFold(text, prefix) {
+			candidates = append(candidates, completion{text: text, kind: kind, detail: detail})
+		}
+	}
+
+	addColumns := func(ref tableRef) {
+		for _, column := range catalog.tableColumns(ref.schema, ref.table) {
+			add(column.Name, COMPLETE_COLUMN, fmt.Sprintf("%s %s", ref.table, column.DataType))
+		}
+	}
+
+	if qualifier != "" {
+		for _, ref := range refs {
+			if strings.EqualFold(ref.alias, qualifier) || (ref.alias == "" && strings.EqualFold(ref.table, qualifier)) {
+				addColumns(ref)
+			}
+		}
+
+		for _, table := range catalog.tables[qualifier] {
+			add(table, COMPLETE_TABLE, qualifier)
+		}
+
+		if len(candidates) == 0 {
+			addColumns(tableRef{table: qualifier})
+		}
+	} else {
+		switch previousKeyword(before) {
+		case "FROM", "JOIN", "UPDATE", "INTO", "TABLE":
+			for _, schema := range catalog.schemas {
+				for _, table := range catalog.tables[schema] {
+					add(table, COMPLETE_TABLE, schema)
+				}
+				add(schema, COMPLETE_SCHEMA, "")
+			}
+
+		default:
+			for _, ref := range refs {
+				addColumns(ref)
+				if ref.alias != "" {
+					add(ref.alias, COMPLETE_ALIAS, ref.table)
+				}
+			}
+
+			for _, schema := range catalog.schemas {
+				for _, table := range catalog.tables[schema] {
+					add(table, COMPLETE_TABLE, schema)
+				}
+				add(schema, COMPLETE_SCHEMA, "")
+			}
+
+			for _, function := range catalog.functions {
+				add(function, COMPLETE_FUNCTION, "")
+			}
+
+			for keyword := range sqlKeywords {
+				if prefix != "" && unicode.IsLower([]rune(prefix)[0]) {
+					keyword = strings.ToLower(keyword)
+				}
+				add(keyword, COMPLETE_KEYWORD, "")
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].kind != candidates[j].kind {
+			return completionOrder[candidates[i].kind] < completionOrder[candidates[j].kind]
+		}
+		return candidates[i].text < candidates[j].text
+	})
+
+	// The same column can come from several tables
+	var unique []completion
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		key := string(candidate.kind) + candidate.text
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, candidate)
+		}
+	}
+
+	return prefix, unique
+}
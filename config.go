@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type ConfirmPolicy string
+
+const (
+	CONFIRM_ALWAYS     ConfirmPolicy = "always"
+	CONFIRM_PRODUCTION ConfirmPolicy = "production"
+	CONFIRM_NEVER      ConfirmPolicy = "never"
+)
+
+var confirmPolicies = []ConfirmPolicy{CONFIRM_ALWAYS, CONFIRM_PRODUCTION, CONFIRM_NEVER}
+
+// Config holds the settings of config.toml. Settings missing from the file
+// keep their defaults.
+type Config struct {
+	// Opened instead of the welcome menu when termtable runs without a command
+	DefaultConnection string `toml:"default_connection"`
+	// Rows shown per page of the lists
+	PageSize        int    `toml:"page_size"`
+	NullDisplay     string `toml:"null_display"`
+	DateFormat      string `toml:"date_format"`
+	TimestampFormat string `toml:"timestamp_format"`
+
+	Theme   Theme         `toml:"theme"`
+	Confirm ConfirmConfig `toml:"confirm"`
+
+	// Bindings to start from, default or vim
+	KeyPreset string `toml:"key_preset"`
+	// Keys of the actions of each view, such as keys.database.editor = ["e"]
+	Keys map[string]map[string][]string `toml:"keys"`
+}
+
+type ConfirmConfig struct {
+	// Which connections ask for the connection name before DROP, TRUNCATE
+	// and DELETE or UPDATE without WHERE
+	Dangerous ConfirmPolicy `toml:"dangerous"`
+	// Ask before leaving the database view with a transaction open
+	QuitTransaction bool `toml:"quit_transaction"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		PageSize:        14,
+		NullDisplay:     "<nil>",
+		DateFormat:      time.DateOnly,
+		TimestampFormat: "2006-01-02 15:04:05.999999Z07:00",
+		Theme:           defaultTheme(),
+		KeyPreset:       "default",
+		Confirm: ConfirmConfig{
+			Dangerous:       CONFIRM_ALWAYS,
+			QuitTransaction: true,
+		},
+	}
+}
+
+// cfg is the configuration in effect, the defaults until loadConfig reads
+// the file.
+var cfg = defaultConfig()
+
+// configPath returns where the config file is read from. An existing file
+// in the user config directory, such as $XDG_CONFIG_HOME/termtable or
+// ~/.config/termtable, wins over ~/.termtable, which is also where the
+// connections are kept.
+func configPath() (string, error) {
+	if configDir, err := os.UserConfigDir(); err == nil {
+		path := filepath.Join(configDir, "termtable", "config.toml")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".termtable", "config.toml"), nil
+}
+
+// loadConfig reads the config file, if there is one, and applies it. Invalid
+// settings keep their defaults and are returned together as one error so
+// they can all be shown at startup.
+func loadConfig() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	config := defaultConfig()
+	metadata, err := toml.DecodeFile(path, &config)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var problems []string
+	for _, key := range metadata.Undecoded() {
+		problems = append(problems, fmt.Sprintf("unknown setting %s", key))
+	}
+	problems = append(problems, config.validate()...)
+
+	cfg = config
+	cfg.Theme.apply()
+	applyKeys(cfg.KeyPreset, cfg.Keys)
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	errs := make([]error, len(problems))
+	for i, problem := range problems {
+		errs[i] = fmt.Errorf("%s: %s", path, problem)
+	}
+	return errors.Join(errs...)
+}
+
+// validate puts back the defaults of invalid settings, returning what was
+// wrong with them.
+func (c *Config) validate() []string {
+	defaults := defaultConfig()
+	var problems []string
+
+	if c.PageSize < 1 {
+		problems = append(problems, "page_size must be at least 1")
+		c.PageSize = defaults.PageSize
+	}
+
+	if c.DateFormat == "" {
+		problems = append(problems, "date_format must not be empty")
+		c.DateFormat = defaults.DateFormat
+	}
+	if c.TimestampFormat == "" {
+		problems = append(problems, "timestamp_format must not be empty")
+		c.TimestampFormat = defaults.TimestampFormat
+	}
+
+	if !slices.Contains(confirmPolicies, c.Confirm.Dangerous) {
+		problems = append(problems, fmt.Sprintf("confirm.dangerous must be one of %s", joinPolicies(confirmPolicies)))
+		c.Confirm.Dangerous = defaults.Confirm.Dangerous
+	}
+
+	if _, ok := keyPresets[c.KeyPreset]; !ok {
+		problems = append(problems, fmt.Sprintf("unknown key_preset %q", c.KeyPreset))
+		c.KeyPreset = defaults.KeyPreset
+	}
+
+	problems = append(problems, c.Theme.validate(defaults.Theme)...)
+	problems = append(problems, validateKeys(c.KeyPreset, c.Keys)...)
+
+	return problems
+}
+
+func joinPolicies(policies []ConfirmPolicy) string {
+	names := make([]string, len(policies))
+	for i, policy := range policies {
+		names[i] = string(policy)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// confirmDangerous reports whether dangerous statements on the connection
+// need confirmation.
+func (c ConfirmConfig) confirmDangerous(params Connection) bool {
+	switch c.Dangerous {
+	case CONFIRM_NEVER:
+		return false
+	case CONFIRM_PRODUCTION:
+		env := strings.ToLower(params.Env)
+		return env == "prod" || env == "production"
+	default:
+		return true
+	}
+}
+
+// displayValue prints a value for the grid with the configured NULL and date
+// formats.
+func displayValue(value any, date bool) string {
+	switch value := value.(type) {
+	case nil:
+		return cfg.NullDisplay
+	case time.Time:
+		if date {
+			return value.Format(cfg.DateFormat)
+		}
+		return value.Format(cfg.TimestampFormat)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
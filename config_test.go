@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestConfigPath(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the user config directory is only under $HOME/.config on linux")
+	}
+
+	home := t.TempDir()
+	xdg := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	check := func(want string) {
+		t.Helper()
+		if got, err := configPath(); err != nil || got != want {
+			t.Errorf("configPath() = %q, %v, want %q", got, err, want)
+		}
+	}
+	create := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check(filepath.Join(home, ".termtable", "config.toml"))
+
+	userConfig := filepath.Join(home, ".config", "termtable", "config.toml")
+	create(userConfig)
+	check(userConfig)
+
+	// With $XDG_CONFIG_HOME set it is the user config directory, not ~/.config
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	check(filepath.Join(home, ".termtable", "config.toml"))
+
+	xdgConfig := filepath.Join(xdg, "termtable", "config.toml")
+	create(xdgConfig)
+	check(xdgConfig)
+}
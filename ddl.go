@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v5"
+)
+
+// TableDDL returns the statements that create a table, for the connection
+// types that can show them.
+func (params Connection) TableDDL(table string) (string, error) {
+	session, err := params.OpenSession()
+
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	source, ok := session.conn.(ddlSource)
+	if !ok {
+		return "", fmt.Errorf("table definitions are not available for %s", params.Kind())
+	}
+
+	return source.TableDDL(table)
+}
+
+// TableDDL reconstructs the CREATE TABLE statement of a table in the public
+// schema along with its constraints and indexes.
+func (c *postgresConn) TableDDL(table string) (string, error) {
+	conn := c.conn
+
+	name := pgx.Identifier{"public", table}.Sanitize()
+
+	rows, err := conn.Query(context.Background(),
+		`SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+			coalesce(pg_get_expr(d.adbin, d.adrelid), '')
+		FROM pg_attribute a
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, name)
+	if err != nil {
+		return "", err
+	}
+
+	var definitions []string
+	for rows.Next() {
+		var column, dataType, defaultValue string
+		var notNull bool
+		if err := rows.Scan(&column, &dataType, &notNull, &defaultValue); err != nil {
+			return "", err
+		}
+
+		definition := fmt.Sprintf("%s %s", pgx.Identifier{column}.Sanitize(), dataType)
+		if notNull {
+			definition += " NOT NULL"
+		}
+		if defaultValue != "" {
+			definition += " DEFAULT " + defaultValue
+		}
+		definitions = append(definitions, definition)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	rows, err = conn.Query(context.Background(),
+		`SELECT conname, pg_get_constraintdef(oid) FROM pg_constraint
+		WHERE conrelid = $1::regclass
+		ORDER BY contype, conname`, name)
+	if err != nil {
+		return "", err
+	}
+
+	for rows.Next() {
+		var constraint, definition string
+		if err := rows.Scan(&constraint, &definition); err != nil {
+			return "", err
+		}
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s %s", pgx.Identifier{constraint}.Sanitize(), definition))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n    %s\n);\n", name, strings.Join(definitions, ",\n    "))
+
+	// Indexes backing constraints are already part of the table definition
+	rows, err = conn.Query(context.Background(),
+		`SELECT indexdef FROM pg_indexes
+		WHERE schemaname = 'public' AND tablename = $1
+			AND indexname NOT IN (SELECT conname FROM pg_constraint WHERE conrelid = $2::regclass)
+		ORDER BY indexname`, table, name)
+	if err != nil {
+		return "", err
+	}
+
+	indexes, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return "", err
+	}
+
+	for _, index := range indexes {
+		fmt.Fprintf(&b, "\n%s;", index)
+	}
+
+	return b.String(), nil
+}
+
+type DDLModel struct {
+	table    string
+	viewport viewport.Model
+	back     bool
+	err      error
+}
+
+func NewDDLModel(params Connection, table string) DDLModel {
+	m := DDLModel{
+		table:    table,
+		viewport: viewport.New(width, height/2),
+	}
+
+	ddl, err := params.TableDDL(table)
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	m.viewport.SetContent(highlightSQL(ddl))
+
+	return m
+}
+
+func (m DDLModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m DDLModel) Update(msg tea.Msg) (DDLModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if key.Matches(msg, pagerKeys.Close) {
+			m.back = true
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m DDLModel) View() string {
+	s := fmt.Sprintf("DDL of %s\n\n", m.table)
+
+	if m.err != nil {
+		s += errorStyle.Render(m.err.Error())
+	} else {
+		s += m.viewport.View()
+	}
+
+	return s + helpStyle.Render("\n\n"+keyHelp(m.viewport.KeyMap.Up, m.viewport.KeyMap.Down, pagerKeys.Close))
+}
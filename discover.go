@@ -0,0 +1,367 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgpassfile"
+	"github.com/jackc/pgservicefile"
+)
+
+type ConnectionSource string
+
+const (
+	PGPASS      ConnectionSource = "pgpass"
+	PGSERVICE   ConnectionSource = "pg_service"
+	ENVIRONMENT ConnectionSource = "environment"
+)
+
+type discoveredConnection struct {
+	conn     Connection
+	source   ConnectionSource
+	selected bool
+}
+
+func pgpassPath() string {
+	if path := os.Getenv("PGPASSFILE"); path != "" {
+		return path
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(homeDir, ".pgpass")
+}
+
+// pgServicePaths returns the service files libpq reads, the user's first.
+func pgServicePaths() []string {
+	var paths []string
+
+	if path := os.Getenv("PGSERVICEFILE"); path != "" {
+		paths = append(paths, path)
+	} else if homeDir, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(homeDir, ".pg_service.conf"))
+	}
+
+	// Without PGSYSCONFDIR fall back to where Debian based packages keep it
+	sysconfDir := os.Getenv("PGSYSCONFDIR")
+	if sysconfDir == "" {
+		sysconfDir = "/etc/postgresql-common"
+	}
+	paths = append(paths, filepath.Join(sysconfDir, "pg_service.conf"))
+
+	return paths
+}
+
+// pgpassPassword looks up the password of a connection in the pgpass file.
+func pgpassPassword(conn Connection) (string, error) {
+	passfile, err := pgpassfile.ReadPassfile(pgpassPath())
+	if err != nil {
+		return "", fmt.Errorf("pgpass: %w", err)
+	}
+
+	port := conn.Port
+	if port == "" {
+		port = "5432"
+	}
+
+	password := passfile.FindPassword(conn.Host, port, conn.Database, conn.User)
+	if password == "" {
+		return "", fmt.Errorf("pgpass: no entry for %s@%s:%s/%s", conn.User, conn.Host, port, conn.Database)
+	}
+
+	return password, nil
+}
+
+// discoverConnections collects the connections described by the pgpass file,
+// the service files and the PG* environment variables. Files that are missing
+// are skipped, unreadable ones are reported.
+func discoverConnections() ([]discoveredConnection, []error) {
+	var found []discoveredConnection
+	var errs []error
+
+	passfile, err := pgpassfile.ReadPassfile(pgpassPath())
+	if err == nil {
+		for _, entry := range passfile.Entries {
+			// Wildcard hosts and users do not name a connection
+			if entry.Hostname == "*" || entry.Username == "*" {
+				continue
+			}
+
+			conn := Connection{
+				Host:     entry.Hostname,
+				Port:     entry.Port,
+				User:     entry.Username,
+				Pass:     entry.Password,
+				Database: entry.Database,
+			}
+			if conn.Port == "*" {
+				conn.Port = "5432"
+			}
+			if conn.Database == "*" {
+				conn.Database = "postgres"
+			}
+			conn.Name = fmt.Sprintf("%s@%s/%s", conn.User, conn.Host, conn.Database)
+
+			found = append(found, discoveredConnection{conn: conn, source: PGPASS})
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("pgpass: %w", err))
+	}
+
+	for _, path := range pgServicePaths() {
+		servicefile, err := pgservicefile.ReadServicefile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		for _, service := range servicefile.Services {
+			conn, err := connectionFromSettings(Connection{}, supportedSettings(service.Settings))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("service %s: %w", service.Name, err))
+				continue
+			}
+			conn.Name = service.Name
+
+			found = append(found, discoveredConnection{conn: conn, source: PGSERVICE})
+		}
+	}
+
+	env := map[string]string{
+		"host":        os.Getenv("PGHOST"),
+		"port":        os.Getenv("PGPORT"),
+		"user":        os.Getenv("PGUSER"),
+		"password":    os.Getenv("PGPASSWORD"),
+		"dbname":      os.Getenv("PGDATABASE"),
+		"sslmode":     os.Getenv("PGSSLMODE"),
+		"sslrootcert": os.Getenv("PGSSLROOTCERT"),
+		"sslcert":     os.Getenv("PGSSLCERT"),
+		"sslkey":      os.Getenv("PGSSLKEY"),
+	}
+	if env["host"] != "" || env["dbname"] != "" {
+		for key, value := range env {
+			if value == "" {
+				delete(env, key)
+			}
+		}
+
+		conn, _ := connectionFromSettings(Connection{}, env)
+		if conn.Host == "" {
+			conn.Host = "localhost"
+		}
+		conn.Name = "environment"
+
+		found = append(found, discoveredConnection{conn: conn, source: ENVIRONMENT})
+	}
+
+	return found, errs
+}
+
+// supportedSettings drops the libpq settings a connection has no field for,
+// such as application_name, so a service file can still be imported.
+func supportedSettings(settings map[string]string) map[string]string {
+	supported := map[string]string{}
+	for key, value := range settings {
+		switch key {
+		case "host", "port", "user", "password", "dbname", "sslmode", "sslrootcert", "sslcert", "sslkey":
+			supported[key] = value
+		}
+	}
+
+	return supported
+}
+
+type discoveredItem struct {
+	discoveredConnection
+	linked bool
+	exists bool
+}
+
+func (i discoveredItem) FilterValue() string { return i.conn.Name }
+
+type discoveredItemDelegate struct{}
+
+func (d discoveredItemDelegate) Height() int                             { return 1 }
+func (d discoveredItemDelegate) Spacing() int                            { return 0 }
+func (d discoveredItemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d discoveredItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(discoveredItem)
+	if !ok {
+		return
+	}
+
+	check := "[ ]"
+	if i.selected {
+		check = "[x]"
+	}
+
+	str := fmt.Sprintf("%s %s", check, i.conn.Name)
+	if index == m.Index() {
+		str = selectedItemStyle.Render("> " + str)
+	} else {
+		str = itemStyle.Render(str)
+	}
+
+	details := fmt.Sprintf("%s@%s:%s/%s  %s", i.conn.User, i.conn.Host, i.conn.Port, i.conn.Database, i.source)
+	if i.linked {
+		details += ", password from pgpass"
+	}
+	if i.exists {
+		details += ", name already saved"
+	}
+
+	fmt.Fprint(w, str+"  "+blurredStyle.Render(details))
+}
+
+// DiscoverConnectionsModel lists the connections found in the libpq files and
+// environment to choose which to save.
+type DiscoverConnectionsModel struct {
+	list   list.Model
+	status string
+	errs   []error
+	back   bool
+}
+
+func NewDiscoverConnectionsModel() DiscoverConnectionsModel {
+	m := DiscoverConnectionsModel{}
+
+	found, errs := discoverConnections()
+	m.errs = errs
+
+	saved := map[string]bool{}
+	if connections, err := ListConnections(); err == nil {
+		for _, conn := range connections {
+			saved[conn.Name] = true
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].conn.Name < found[j].conn.Name
+	})
+
+	items := make([]list.Item, len(found))
+	for i, d := range found {
+		items[i] = discoveredItem{discoveredConnection: d, exists: saved[d.conn.Name]}
+	}
+
+	l := list.New(items, discoveredItemDelegate{}, width, cfg.PageSize)
+	l.Title = "Import connections"
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	m.list = l
+
+	if len(found) == 0 {
+		m.status = "No connections found in pgpass, pg_service.conf or PG* variables"
+	}
+
+	return m
+}
+
+func (m DiscoverConnectionsModel) Init() tea.Cmd {
+	return nil
+}
+
+// save stores the selected connections, leaving saved names untouched.
+func (m *DiscoverConnectionsModel) save() {
+	saved, skipped := 0, 0
+	m.errs = nil
+
+	for i, listItem := range m.list.Items() {
+		item := listItem.(discoveredItem)
+		if !item.selected {
+			continue
+		}
+		if item.exists {
+			skipped++
+			continue
+		}
+
+		conn := item.conn
+		conn.PgpassLinked = item.linked
+		if err := SaveConnection(conn); err != nil {
+			m.errs = append(m.errs, fmt.Errorf("%s: %w", conn.Name, err))
+			continue
+		}
+
+		item.selected = false
+		item.exists = true
+		m.list.SetItem(i, item)
+		saved++
+	}
+
+	m.status = fmt.Sprintf("Saved %d connections", saved)
+	if skipped > 0 {
+		m.status += fmt.Sprintf(", skipped %d already saved", skipped)
+	}
+}
+
+func (m DiscoverConnectionsModel) Update(msg tea.Msg) (DiscoverConnectionsModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, discoverKeys.Back):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, discoverKeys.Toggle):
+			if item, ok := m.list.SelectedItem().(discoveredItem); ok {
+				item.selected = !item.selected
+				m.list.SetItem(m.list.Index(), item)
+			}
+			return m, nil
+
+		case key.Matches(msg, discoverKeys.SelectAll):
+			for i, listItem := range m.list.Items() {
+				item := listItem.(discoveredItem)
+				item.selected = !item.exists
+				m.list.SetItem(i, item)
+			}
+			return m, nil
+
+		// Keep the password in pgpass rather than copying it to the keyring
+		case key.Matches(msg, discoverKeys.Link):
+			if item, ok := m.list.SelectedItem().(discoveredItem); ok {
+				item.linked = !item.linked
+				m.list.SetItem(m.list.Index(), item)
+			}
+			return m, nil
+
+		case key.Matches(msg, discoverKeys.Save):
+			m.save()
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m DiscoverConnectionsModel) View() string {
+	s := m.list.View()
+
+	if m.status != "" {
+		s += "\n" + blurredStyle.Render(m.status)
+	}
+	for _, err := range m.errs {
+		s += "\n" + errorStyle.Render(err.Error())
+	}
+
+	return s + helpStyle.Render("\n"+keyHelp(discoverKeys.Toggle, discoverKeys.SelectAll, discoverKeys.Link, discoverKeys.Save,
+		discoverKeys.Back))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverConnections(t *testing.T) {
+	dir := t.TempDir()
+
+	pgpass := filepath.Join(dir, "pgpass")
+	passfile := "# comment\n" +
+		"db.example.com:5433:app:alice:secret\n" +
+		"*:5432:app:bob:wildcard-host\n" +
+		"db.example.com:5432:app:*:wildcard-user\n" +
+		"localhost:*:*:carol:any\n"
+	if err := os.WriteFile(pgpass, []byte(passfile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	services := filepath.Join(dir, "pg_service.conf")
+	servicefile := "[reporting]\n" +
+		"host=reports.example.com\n" +
+		"dbname=reports\n" +
+		"user=report\n" +
+		"application_name=psql\n" +
+		"connect_timeout=10\n"
+	if err := os.WriteFile(services, []byte(servicefile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PGPASSFILE", pgpass)
+	t.Setenv("PGSERVICEFILE", services)
+	t.Setenv("PGSYSCONFDIR", filepath.Join(dir, "missing"))
+	for _, name := range []string{"PGHOST", "PGPORT", "PGUSER", "PGPASSWORD", "PGDATABASE", "PGSSLMODE", "PGSSLROOTCERT", "PGSSLCERT", "PGSSLKEY"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("PGDATABASE", "envdb")
+	t.Setenv("PGUSER", "dave")
+	t.Setenv("PGSSLMODE", "require")
+
+	found, errs := discoverConnections()
+	if len(errs) != 0 {
+		t.Fatalf("errs = %v", errs)
+	}
+
+	want := []discoveredConnection{
+		{
+			conn: Connection{
+				Name: "alice@db.example.com/app", Host: "db.example.com", Port: "5433",
+				User: "alice", Pass: "secret", Database: "app",
+			},
+			source: PGPASS,
+		},
+		{
+			conn: Connection{
+				Name: "carol@localhost/postgres", Host: "localhost", Port: "5432",
+				User: "carol", Pass: "any", Database: "postgres",
+			},
+			source: PGPASS,
+		},
+		{
+			conn: Connection{
+				Name: "reporting", Host: "reports.example.com", User: "report", Database: "reports",
+			},
+			source: PGSERVICE,
+		},
+		{
+			conn: Connection{
+				Name: "environment", Host: "localhost", User: "dave", Database: "envdb", SSLMode: "require",
+			},
+			source: ENVIRONMENT,
+		},
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("discoverConnections()\n got %+v\nwant %+v", found, want)
+	}
+}
+
+func TestDiscoverConnectionsReportsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	services := filepath.Join(dir, "pg_service.conf")
+	if err := os.WriteFile(services, []byte("[broken]\nnot a setting\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PGPASSFILE", filepath.Join(dir, "missing"))
+	t.Setenv("PGSERVICEFILE", services)
+	t.Setenv("PGSYSCONFDIR", filepath.Join(dir, "missing"))
+	t.Setenv("PGHOST", "")
+	t.Setenv("PGDATABASE", "")
+
+	found, errs := discoverConnections()
+	if len(found) != 0 || len(errs) != 1 {
+		t.Errorf("found %v, errs %v, want only the service file error", found, errs)
+	}
+}
+
+func TestSupportedSettings(t *testing.T) {
+	got := supportedSettings(map[string]string{
+		"host":             "db",
+		"dbname":           "app",
+		"sslmode":          "verify-full",
+		"application_name": "psql",
+		"options":          "-c search_path=app",
+	})
+
+	want := map[string]string{"host": "db", "dbname": "app", "sslmode": "verify-full"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("supportedSettings() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type DriverKind string
+
+const (
+	POSTGRES DriverKind = "postgres"
+	SQLITE   DriverKind = "sqlite"
+	MYSQL    DriverKind = "mysql"
+	DUCKDB   DriverKind = "duckdb"
+)
+
+// driverKinds lists the kinds in the order the connection form cycles them.
+var driverKinds = []DriverKind{POSTGRES, MYSQL, SQLITE, DUCKDB}
+
+// Driver opens connections to one kind of database.
+type Driver interface {
+	Connect(params Connection) (DriverConn, error)
+}
+
+// DriverConn is an open connection. Statements run one at a time, Cancel may
+// be called from another goroutine to interrupt the one running.
+type DriverConn interface {
+	// Tables lists the tables and views that can be browsed
+	Tables() ([]string, error)
+	// Columns describes the columns of a table in order
+	Columns(table string) ([]Column, error)
+	Query(ctx context.Context, sql string, args ...any) (Table, error)
+	QueryText(ctx context.Context, sql string) (ResultSet, error)
+	Exec(ctx context.Context, sql string) error
+	Cancel() error
+	TxStatus() TxStatus
+	Closed() bool
+	Close()
+}
+
+// Optional features of a connection, checked for with a type assertion.
+type (
+	ddlSource interface {
+		TableDDL(table string) (string, error)
+	}
+
+	catalogSource interface {
+		Catalog() (Catalog, error)
+	}
+)
+
+var drivers = map[DriverKind]Driver{
+	POSTGRES: postgresDriver{},
+	SQLITE:   sqliteDriver{},
+	MYSQL:    mysqlDriver{},
+	DUCKDB:   duckdbDriver{},
+}
+
+type PlaceholderStyle string
+
+const (
+	PLACEHOLDER_DOLLAR   PlaceholderStyle = "$n"
+	PLACEHOLDER_QUESTION PlaceholderStyle = "?"
+)
+
+// placeholderStyles are the positional placeholders of the kinds that do not
+// number them with $1.
+var placeholderStyles = map[DriverKind]PlaceholderStyle{
+	MYSQL: PLACEHOLDER_QUESTION,
+}
+
+// defaultPorts are the ports servers listen on unless configured otherwise.
+var defaultPorts = map[DriverKind]string{
+	POSTGRES: "5432",
+	MYSQL:    "3306",
+}
+
+// Kind returns the kind of database, postgres for connections saved before
+// there was a choice.
+func (params Connection) Kind() DriverKind {
+	if params.Driver == "" {
+		return POSTGRES
+	}
+
+	return params.Driver
+}
+
+// server reports whether the connection is to a database server rather than
+// a local file.
+func (params Connection) server() bool {
+	_, ok := defaultPorts[params.Kind()]
+	return ok
+}
+
+// Target describes where the connection points, the server and database or
+// the database file.
+func (params Connection) Target() string {
+	if !params.server() {
+		if params.Database == "" {
+			return ":memory:"
+		}
+		return params.Database
+	}
+
+	return fmt.Sprintf("%s:%s/%s", params.Host, params.Port, params.Database)
+}
+
+// Placeholders returns how statements on the connection take parameters.
+func (params Connection) Placeholders() PlaceholderStyle {
+	if style, ok := placeholderStyles[params.Kind()]; ok {
+		return style
+	}
+
+	return PLACEHOLDER_DOLLAR
+}
+
+func (params Connection) driver() (Driver, error) {
+	driver, ok := drivers[params.Kind()]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection type %q", params.Kind())
+	}
+
+	return driver, nil
+}
+
+// firstKeyword returns the first word of a statement in upper case.
+func firstKeyword(sql string) string {
+	tokens := significantTokens(sql)
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	return strings.ToUpper(tokens[0].text)
+}
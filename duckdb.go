@@ -0,0 +1,261 @@
+//go:build duckdb
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+)
+
+// dataFileExtensions are the files duckdb reads directly, compressed or not.
+var dataFileExtensions = []string{".parquet", ".csv", ".tsv", ".json", ".ndjson", ".jsonl"}
+
+type duckdbDriver struct{}
+
+// Connect opens a duckdb database file. A Parquet, CSV or JSON file, or a
+// glob of them, opens an in-memory database with a view over the data, and
+// an empty path an empty one to query files with SQL.
+func (duckdbDriver) Connect(params Connection) (DriverConn, error) {
+	path := expandHome(params.Database)
+	dataFile := isDataFile(path)
+
+	if path != "" && !strings.ContainsAny(path, "*?[") {
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("duckdb: %w", err)
+		}
+	}
+
+	dsn := ""
+	if !dataFile && path != "" {
+		dsn = path
+		if params.ReadOnly {
+			dsn += "?access_mode=read_only"
+		}
+	}
+
+	db, err := sql.Open("duckdb", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("duckdb: %w", err)
+	}
+
+	conn, err := openSQLConn(db, duckdbDialect{})
+	if err != nil {
+		return nil, fmt.Errorf("duckdb: %w", err)
+	}
+
+	if dataFile {
+		statement := fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s",
+			duckdbIdentifier(dataFileTable(path)), duckdbString(path))
+		if err := conn.Exec(context.Background(), statement); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("duckdb: %w", err)
+		}
+	}
+
+	return &duckdbConn{conn}, nil
+}
+
+func isDataFile(path string) bool {
+	path = strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ".zst")
+	return slices.Contains(dataFileExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// dataFileTable names the view over a data file after the file, or after its
+// directory for a glob.
+func dataFileTable(path string) string {
+	name := filepath.Base(path)
+	if strings.ContainsAny(name, "*?[") {
+		name = filepath.Base(filepath.Dir(path))
+	}
+
+	name, _, _ = strings.Cut(name, ".")
+	return name
+}
+
+func duckdbIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func duckdbString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+type duckdbConn struct {
+	*sqlConn
+}
+
+func (c *duckdbConn) Tables() ([]string, error) {
+	return c.strings(`SELECT table_name FROM information_schema.tables
+		WHERE table_catalog = current_database() AND table_schema = current_schema()
+		ORDER BY table_name`)
+}
+
+func (c *duckdbConn) Columns(table string) ([]Column, error) {
+	return c.columns(`SELECT column_name, data_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_catalog = current_database() AND table_schema = current_schema()
+			AND table_name = ?
+		ORDER BY ordinal_position`, table)
+}
+
+// TableDDL returns the statements duckdb keeps for the table or view and its
+// indexes.
+func (c *duckdbConn) TableDDL(table string) (string, error) {
+	statements, err := c.strings(`
+		SELECT sql FROM duckdb_tables()
+		WHERE database_name = current_database() AND schema_name = current_schema() AND table_name = ?
+		UNION ALL
+		SELECT sql FROM duckdb_views()
+		WHERE database_name = current_database() AND schema_name = current_schema() AND view_name = ?
+		UNION ALL
+		SELECT sql FROM duckdb_indexes()
+		WHERE database_name = current_database() AND schema_name = current_schema() AND table_name = ?
+			AND sql IS NOT NULL`, table, table, table)
+	if err != nil {
+		return "", err
+	}
+
+	if len(statements) == 0 {
+		return "", fmt.Errorf("no table named %s", table)
+	}
+
+	return strings.Join(statements, "\n\n"), nil
+}
+
+type duckdbDialect struct{}
+
+func (duckdbDialect) valueKind(column *sql.ColumnType) ValueKind {
+	declared := column.DatabaseTypeName()
+
+	switch {
+	case declared == "BOOLEAN":
+		return VALUE_BOOL
+	case declared == "JSON",
+		strings.HasSuffix(declared, "]"),
+		strings.HasPrefix(declared, "STRUCT"),
+		strings.HasPrefix(declared, "MAP"):
+		return VALUE_JSON
+	case strings.HasSuffix(declared, "INT"),
+		declared == "INTEGER",
+		declared == "UINTEGER",
+		declared == "FLOAT",
+		declared == "DOUBLE",
+		strings.HasPrefix(declared, "DECIMAL"):
+		return VALUE_NUMBER
+	default:
+		return VALUE_TEXT
+	}
+}
+
+// formatValue prints values the way the duckdb shell does, with lists and
+// structs as JSON.
+func (duckdbDialect) formatValue(column *sql.ColumnType, value any) string {
+	declared := column.DatabaseTypeName()
+
+	switch value := value.(type) {
+	case duckdb.Decimal:
+		return formatDecimal(value.Value, int(value.Scale))
+	case duckdb.Interval:
+		return formatInterval(value)
+	case *big.Int:
+		return value.String()
+	case []any, map[string]any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	case []byte:
+		if declared == "UUID" && len(value) == 16 {
+			text := hex.EncodeToString(value)
+			return fmt.Sprintf("%s-%s-%s-%s-%s", text[:8], text[8:12], text[12:16], text[16:20], text[20:])
+		}
+		if declared == "BLOB" {
+			return "0x" + strings.ToUpper(hex.EncodeToString(value))
+		}
+		return string(value)
+	case time.Time:
+		switch declared {
+		case "DATE":
+			return value.Format(time.DateOnly)
+		case "TIME":
+			return value.Format("15:04:05.999999")
+		case "TIMESTAMPTZ":
+			return value.Format(time.RFC3339Nano)
+		default:
+			return value.Format("2006-01-02 15:04:05.999999")
+		}
+	default:
+		return formatSQLValue(value)
+	}
+}
+
+// formatInterval leaves out the parts of an interval that are zero.
+func formatInterval(interval duckdb.Interval) string {
+	var parts []string
+	if interval.Months != 0 {
+		parts = append(parts, fmt.Sprintf("%d months", interval.Months))
+	}
+	if interval.Days != 0 {
+		parts = append(parts, fmt.Sprintf("%d days", interval.Days))
+	}
+	if interval.Micros != 0 || len(parts) == 0 {
+		parts = append(parts, (time.Duration(interval.Micros) * time.Microsecond).String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// formatDecimal places the decimal point in an unscaled value.
+func formatDecimal(value *big.Int, scale int) string {
+	digits := new(big.Int).Abs(value).String()
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+
+	if value.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// changes is never needed as duckdb answers every statement with a result.
+func (duckdbDialect) changes() string {
+	return "SELECT 0"
+}
+
+// changeCount reads the Count column duckdb answers changes with, and the
+// Success column of other statements.
+func (duckdbDialect) changeCount(statement string, result ResultSet) (int64, bool) {
+	if len(result.fields) != 1 || (result.fields[0] != "Count" && result.fields[0] != "Success") {
+		return 0, false
+	}
+
+	switch firstKeyword(statement) {
+	case "SELECT", "WITH", "FROM", "VALUES", "TABLE", "SHOW", "DESCRIBE",
+		"SUMMARIZE", "EXPLAIN", "PRAGMA", "CALL":
+		return 0, false
+	}
+
+	var count int64
+	if result.fields[0] == "Count" && len(result.values) == 1 && result.values[0][0] != nil {
+		count, _ = strconv.ParseInt(*result.values[0][0], 10, 64)
+	}
+
+	return count, true
+}
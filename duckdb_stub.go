@@ -0,0 +1,13 @@
+//go:build !duckdb
+
+package main
+
+import "errors"
+
+type duckdbDriver struct{}
+
+// Connect explains how to get duckdb support, which needs cgo and is left out
+// of the default build.
+func (duckdbDriver) Connect(params Connection) (DriverConn, error) {
+	return nil, errors.New("duckdb: termtable was built without duckdb support, install it with go install -tags duckdb")
+}
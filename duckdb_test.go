@@ -0,0 +1,93 @@
+//go:build duckdb
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/marcboeker/go-duckdb"
+)
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		value int64
+		scale int
+		want  string
+	}{
+		{value: 12345, scale: 2, want: "123.45"},
+		{value: -12345, scale: 2, want: "-123.45"},
+		{value: 5, scale: 3, want: "0.005"},
+		{value: -5, scale: 3, want: "-0.005"},
+		{value: 100, scale: 2, want: "1.00"},
+		{value: 0, scale: 2, want: "0.00"},
+		{value: 42, scale: 0, want: "42"},
+	}
+
+	for _, test := range tests {
+		if got := formatDecimal(big.NewInt(test.value), test.scale); got != test.want {
+			t.Errorf("formatDecimal(%d, %d) = %q, want %q", test.value, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	tests := []struct {
+		interval duckdb.Interval
+		want     string
+	}{
+		{interval: duckdb.Interval{}, want: "0s"},
+		{interval: duckdb.Interval{Months: 14}, want: "14 months"},
+		{interval: duckdb.Interval{Days: 3, Micros: 90_000_000}, want: "3 days 1m30s"},
+		{interval: duckdb.Interval{Months: 1, Days: -2}, want: "1 months -2 days"},
+		{interval: duckdb.Interval{Micros: 1500}, want: "1.5ms"},
+	}
+
+	for _, test := range tests {
+		if got := formatInterval(test.interval); got != test.want {
+			t.Errorf("formatInterval(%+v) = %q, want %q", test.interval, got, test.want)
+		}
+	}
+}
+
+func TestDuckDBChangeCount(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		statement string
+		result    ResultSet
+		want      int64
+		ok        bool
+	}{
+		{
+			statement: "insert into t values (1), (2)",
+			result:    ResultSet{fields: []string{"Count"}, values: [][]*string{{text("2")}}},
+			want:      2,
+			ok:        true,
+		},
+		{
+			statement: "create table t (id int)",
+			result:    ResultSet{fields: []string{"Success"}},
+			ok:        true,
+		},
+		{
+			statement: "select count(*) as \"Count\" from t",
+			result:    ResultSet{fields: []string{"Count"}, values: [][]*string{{text("7")}}},
+		},
+		{
+			statement: "with c as (select 1) select 1 as \"Success\" from c",
+			result:    ResultSet{fields: []string{"Success"}, values: [][]*string{{text("1")}}},
+		},
+		{
+			statement: "update t set id = 1",
+			result:    ResultSet{fields: []string{"id", "Count"}},
+		},
+	}
+
+	for _, test := range tests {
+		got, ok := duckdbDialect{}.changeCount(test.statement, test.result)
+		if got != test.want || ok != test.ok {
+			t.Errorf("changeCount(%q) = %d, %v, want %d, %v", test.statement, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// QueryEditor is the statement editor of the database view with a completion
+// popup sourced from the catalog.
+type QueryEditor struct {
+	textarea         textarea.Model
+	catalog          Catalog
+	completions      []completion
+	completionIndex  int
+	completionPrefix string
+}
+
+func NewQueryEditor() QueryEditor {
+	t := textarea.New()
+	t.Placeholder = "SELECT ..."
+	t.Cursor.Style = cursorStyle
+	t.SetWidth(width / 2)
+	t.SetHeight(editorHeight)
+
+	return QueryEditor{textarea: t}
+}
+
+func (e QueryEditor) Value() string {
+	return e.textarea.Value()
+}
+
+func (e *QueryEditor) SetValue(s string) {
+	e.textarea.SetValue(s)
+	e.completions = nil
+}
+
+func (e *QueryEditor) Focus() tea.Cmd {
+	return e.textarea.Focus()
+}
+
+func (e *QueryEditor) Blur() {
+	e.textarea.Blur()
+	e.completions = nil
+}
+
+// cursorOffset returns the position of the cursor in runes from the start of
+// the value.
+func (e QueryEditor) cursorOffset() int {
+	offset := 0
+	lines := strings.Split(e.textarea.Value(), "\n")
+	for _, line := range lines[:min(e.textarea.Line(), len(lines))] {
+		offset += len([]rune(line)) + 1
+	}
+
+	info := e.textarea.LineInfo()
+	return offset + info.StartColumn + info.ColumnOffset
+}
+
+func (e QueryEditor) completing() bool {
+	return len(e.completions) > 0
+}
+
+func (e *QueryEditor) updateCompletions() {
+	info := e.textarea.LineInfo()
+	col := info.StartColumn + info.ColumnOffset
+
+	e.completionPrefix, e.completions = completeSQL(e.catalog, e.textarea.Value(), e.textarea.Line(), col)
+	e.completionIndex = 0
+}
+
+// acceptCompletion replaces the prefix being typed with the selected
+// candidate.
+func (e *QueryEditor) acceptCompletion() {
+	candidate := e.completions[e.completionIndex]
+
+	for range []rune(e.completionPrefix) {
+		e.textarea, _ = e.textarea.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	e.textarea.InsertString(candidate.text)
+
+	e.completions = nil
+}
+
+func (e QueryEditor) Update(msg tea.Msg) (QueryEditor, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if e.completing() {
+			switch {
+			case key.Matches(msg, completionKeys.Accept):
+				e.acceptCompletion()
+				return e, nil
+
+			case key.Matches(msg, completionKeys.Prev):
+				e.completionIndex = (e.completionIndex - 1 + len(e.completions)) % len(e.completions)
+				return e, nil
+
+			case key.Matches(msg, completionKeys.Next):
+				e.completionIndex = (e.completionIndex + 1) % len(e.completions)
+				return e, nil
+
+			case key.Matches(msg, completionKeys.Close):
+				e.completions = nil
+				return e, nil
+			}
+		} else if key.Matches(msg, editorKeys.Complete) {
+			// Complete on demand, accepting a single candidate straight away
+			e.updateCompletions()
+			if len(e.completions) == 1 {
+				e.acceptCompletion()
+			}
+			return e, nil
+		}
+
+		var cmd tea.Cmd
+		e.textarea, cmd = e.textarea.Update(msg)
+
+		switch msg.Type {
+		case tea.KeyRunes, tea.KeyBackspace:
+			e.updateCompletions()
+		default:
+			e.completions = nil
+		}
+
+		return e, cmd
+	}
+
+	var cmd tea.Cmd
+	e.textarea, cmd = e.textarea.Update(msg)
+	return e, cmd
+}
+
+func (e QueryEditor) completionView() string {
+	var b strings.Builder
+
+	start := max(0, e.completionIndex-maxCompletions+1)
+	for i := start; i < len(e.completions) && i < start+maxCompletions; i++ {
+		c := e.completions[i]
+		line := fmt.Sprintf("%-24s %s", c.text, blurredStyle.Render(strings.TrimSpace(string(c.kind)+" "+c.detail)))
+
+		if i == e.completionIndex {
+			b.WriteString(selectedItemStyle.Render("> ") + line)
+		} else {
+			b.WriteString("  " + line)
+		}
+
+		if i < len(e.completions)-1 && i < start+maxCompletions-1 {
+			b.WriteRune('\n')
+		}
+	}
+
+	return b.String()
+}
+
+// View draws the statement highlighted, falling back to the textarea for its
+// placeholder while empty.
+func (e QueryEditor) View() string {
+	if e.textarea.Value() == "" {
+		return e.textarea.View()
+	}
+
+	row, col := -1, 0
+	if e.textarea.Focused() {
+		info := e.textarea.LineInfo()
+		row, col = e.textarea.Line(), info.StartColumn+info.ColumnOffset
+	}
+
+	return highlightedEditorView(e.textarea.Value(), row, col, e.textarea.Width(), editorHeight)
+}
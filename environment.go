@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Colours of the common environment names, used when a connection has a tag
+// but no colour of its own
+var envColors = map[string]string{
+	"prod":        RED,
+	"production":  RED,
+	"staging":     YELLOW,
+	"stage":       YELLOW,
+	"test":        MAGENTA,
+	"qa":          MAGENTA,
+	"dev":         GREEN,
+	"development": GREEN,
+	"local":       BLUE,
+}
+
+// EnvColor returns the colour of the connection, "" when it has none.
+func (params Connection) EnvColor() string {
+	if params.Color != "" {
+		return params.Color
+	}
+
+	return envColors[strings.ToLower(params.Env)]
+}
+
+// validateColor checks the colour of the connection is one lipgloss can
+// render, the same as the colours of the theme.
+func (params Connection) validateColor() error {
+	if params.Color != "" && !colorPattern.MatchString(params.Color) {
+		return fmt.Errorf("colour must be an ansi number or #hex colour, not %q", params.Color)
+	}
+
+	return nil
+}
+
+// envTag renders the environment of the connection as a coloured label.
+func (params Connection) envTag() string {
+	if params.Env == "" {
+		return ""
+	}
+
+	style := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(WHITE))
+	if color := params.EnvColor(); color != "" {
+		style = style.Background(lipgloss.Color(color))
+	}
+
+	return style.Render(" " + strings.ToUpper(params.Env) + " ")
+}
+
+// frameStyles returns the blurred and focused border styles of the database
+// view tinted with the colour of the connection. Focus is shown with a thick
+// border since both share the colour.
+func (params Connection) frameStyles() (lipgloss.Style, lipgloss.Style) {
+	color := params.EnvColor()
+	if color == "" {
+		return modelStyle, focusedModelStyle
+	}
+
+	return modelStyle.Copy().BorderForeground(lipgloss.Color(color)),
+		focusedModelStyle.Copy().
+			BorderStyle(lipgloss.ThickBorder()).
+			BorderForeground(lipgloss.Color(color))
+}
@@ -1,21 +1,120 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"sort"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+const (
+	FAVORITES_GROUP = "Favorites"
+	DEFAULT_GROUP   = "Ungrouped"
+)
+
+type ConnectionOrder string
+
+const (
+	BY_NAME   ConnectionOrder = "name"
+	BY_RECENT ConnectionOrder = "recent"
+)
+
+type connectionItem Connection
+
+func (i connectionItem) FilterValue() string { return i.Name }
+
+type groupItem struct {
+	name      string
+	count     int
+	collapsed bool
+}
+
+func (i groupItem) FilterValue() string { return "" }
+
+type connectionItemDelegate struct{}
+
+func (d connectionItemDelegate) Height() int                             { return 1 }
+func (d connectionItemDelegate) Spacing() int                            { return 0 }
+func (d connectionItemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d connectionItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	var str string
+
+	switch i := listItem.(type) {
+	case groupItem:
+		arrow := "▾"
+		if i.collapsed {
+			arrow = "▸"
+		}
+		str = fmt.Sprintf("%s %s %s", arrow, i.name, blurredStyle.Render(fmt.Sprintf("(%d)", i.count)))
+
+		if index == m.Index() {
+			str = selectedItemStyle.Render("> ") + str
+		} else {
+			str = "  " + str
+		}
+
+	case connectionItem:
+		name := "  " + i.Name
+		if i.Favorite {
+			name = "★ " + i.Name
+		}
+
+		if index == m.Index() {
+			str = selectedItemStyle.Render("> " + name)
+		} else {
+			str = itemStyle.Render(name)
+		}
+
+		if tag := Connection(i).envTag(); tag != "" {
+			str += " " + tag
+		}
+		if !i.LastUsed.IsZero() {
+			str += " " + blurredStyle.Render(timeAgo(i.LastUsed))
+		}
+
+	default:
+		return
+	}
+
+	fmt.Fprint(w, str)
+}
+
+// timeAgo formats how long ago t was in its largest unit.
+func timeAgo(t time.Time) string {
+	d := time.Since(t)
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 type ExistingConnectionsModel struct {
 	list               list.Model
 	connections        []Connection
+	collapsed          map[string]bool
+	order              ConnectionOrder
 	selectedConnection *Connection
 	back               bool
+	err                error
 }
 
 func NewExistingConnectionsModel() ExistingConnectionsModel {
-	existingConnectionsModel := ExistingConnectionsModel{}
+	existingConnectionsModel := ExistingConnectionsModel{
+		collapsed: map[string]bool{},
+		order:     BY_NAME,
+	}
 
 	connections, err := ListConnections()
 
@@ -23,26 +122,77 @@ func NewExistingConnectionsModel() ExistingConnectionsModel {
 		return existingConnectionsModel
 	}
 
-	items := []list.Item{}
-
-	for _, conn := range connections {
-		items = append(items, item(conn.Name))
-	}
-
-	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
+	l := list.New(nil, connectionItemDelegate{}, defaultWidth, cfg.PageSize)
 	l.Title = "Choose a connection"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
 	l.Styles.Title = titleStyle
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{connectionsKeys.Select, connectionsKeys.Favorite, connectionsKeys.Sort}
+	}
 
 	existingConnectionsModel.list = l
 	existingConnectionsModel.connections = connections
+	existingConnectionsModel.setItems()
 
 	return existingConnectionsModel
 }
 
+// setItems lays the connections out with favorites pinned first, then one
+// folder per group in name order and the ungrouped ones last.
+func (m *ExistingConnectionsModel) setItems() {
+	connections := make([]Connection, len(m.connections))
+	copy(connections, m.connections)
+
+	sort.SliceStable(connections, func(i, j int) bool {
+		if m.order == BY_RECENT && !connections[i].LastUsed.Equal(connections[j].LastUsed) {
+			return connections[i].LastUsed.After(connections[j].LastUsed)
+		}
+		return connections[i].Name < connections[j].Name
+	})
+
+	groups := map[string][]Connection{}
+	var names []string
+	for _, conn := range connections {
+		group := conn.Group
+		switch {
+		case conn.Favorite:
+			group = FAVORITES_GROUP
+		case group == "":
+			group = DEFAULT_GROUP
+		}
+
+		if _, ok := groups[group]; !ok && group != FAVORITES_GROUP && group != DEFAULT_GROUP {
+			names = append(names, group)
+		}
+		groups[group] = append(groups[group], conn)
+	}
+
+	sort.Strings(names)
+	names = append([]string{FAVORITES_GROUP}, names...)
+	names = append(names, DEFAULT_GROUP)
+
+	items := []list.Item{}
+	for _, name := range names {
+		conns := groups[name]
+		if len(conns) == 0 {
+			continue
+		}
+
+		items = append(items, groupItem{name: name, count: len(conns), collapsed: m.collapsed[name]})
+		if m.collapsed[name] {
+			continue
+		}
+		for _, conn := range conns {
+			items = append(items, connectionItem(conn))
+		}
+	}
+
+	m.list.SetItems(items)
+}
+
 func (m ExistingConnectionsModel) Init() tea.Cmd {
 	return nil
 }
@@ -54,32 +204,53 @@ func (m ExistingConnectionsModel) Update(msg tea.Msg) (ExistingConnectionsModel,
 		return m, nil
 
 	case tea.KeyMsg:
-		switch keypress := msg.String(); keypress {
-		case "q", "ctrl+c":
+		switch {
+		case key.Matches(msg, connectionsKeys.Quit):
 			m.back = true
 			return m, nil
 
-		case "enter":
-			i, ok := m.list.SelectedItem().(item)
-			if ok {
-				choice := string(i)
+		case key.Matches(msg, connectionsKeys.Sort):
+			if m.order == BY_NAME {
+				m.order = BY_RECENT
+			} else {
+				m.order = BY_NAME
+			}
+			m.setItems()
+			return m, nil
 
-				for _, v := range m.connections {
-					if v.Name == choice {
-						m.selectedConnection = &v
+		case key.Matches(msg, connectionsKeys.Favorite):
+			i, ok := m.list.SelectedItem().(connectionItem)
+			if !ok {
+				return m, nil
+			}
 
-						user, pass, err := GetConnectionFromKeyring(v.Name)
+			for j := range m.connections {
+				if m.connections[j].Name == i.Name {
+					m.connections[j].Favorite = !m.connections[j].Favorite
+					m.err = updateLocalDbConn(m.connections[j])
+				}
+			}
+			m.setItems()
+			return m, nil
+
+		case key.Matches(msg, connectionsKeys.Select, connectionsKeys.ToggleGroup):
+			switch i := m.list.SelectedItem().(type) {
+			case groupItem:
+				m.collapsed[i.name] = !m.collapsed[i.name]
+				m.setItems()
 
-						if err != nil {
-							log.Fatal("Could not get user and password for connection from keyring: ", err)
-						}
+			case connectionItem:
+				if !key.Matches(msg, connectionsKeys.Select) {
+					break
+				}
 
-						m.selectedConnection.User = user
-						m.selectedConnection.Pass = pass
+				conn, err := Connection(i).withCredentials()
 
-						break
-					}
+				if err != nil {
+					log.Fatal("Could not get user and password for connection from keyring: ", err)
 				}
+
+				m.selectedConnection = &conn
 			}
 			return m, nil
 		}
@@ -91,5 +262,13 @@ func (m ExistingConnectionsModel) Update(msg tea.Msg) (ExistingConnectionsModel,
 }
 
 func (m ExistingConnectionsModel) View() string {
-	return m.list.View()
+	s := m.list.View()
+
+	s += helpStyle.Render(fmt.Sprintf("\nsorted by %s", m.order))
+
+	if m.err != nil {
+		s += "\n" + errorStyle.Render(m.err.Error())
+	}
+
+	return s
 }
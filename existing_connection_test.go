@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestSetItems(t *testing.T) {
+	now := time.Now()
+	connections := []Connection{
+		{Name: "orders", Group: "prod", LastUsed: now},
+		{Name: "archive"},
+		{Name: "billing", Group: "prod", LastUsed: now.Add(-time.Hour)},
+		{Name: "analytics", Group: "prod", Favorite: true},
+		{Name: "local", LastUsed: now.Add(-time.Minute)},
+		{Name: "reports", Group: "dev"},
+	}
+
+	tests := []struct {
+		name      string
+		order     ConnectionOrder
+		collapsed map[string]bool
+		want      []string
+	}{
+		{
+			name:  "by name",
+			order: BY_NAME,
+			want: []string{
+				"Favorites (1)", "analytics",
+				"dev (1)", "reports",
+				"prod (2)", "billing", "orders",
+				"Ungrouped (2)", "archive", "local",
+			},
+		},
+		{
+			name:  "by recent",
+			order: BY_RECENT,
+			want: []string{
+				"Favorites (1)", "analytics",
+				"dev (1)", "reports",
+				"prod (2)", "orders", "billing",
+				"Ungrouped (2)", "local", "archive",
+			},
+		},
+		{
+			name:      "collapsed groups",
+			order:     BY_NAME,
+			collapsed: map[string]bool{"prod": true, FAVORITES_GROUP: true},
+			want: []string{
+				"Favorites (1) collapsed",
+				"dev (1)", "reports",
+				"prod (2) collapsed",
+				"Ungrouped (2)", "archive", "local",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		m := ExistingConnectionsModel{
+			list:        list.New(nil, connectionItemDelegate{}, 80, 20),
+			connections: connections,
+			collapsed:   test.collapsed,
+			order:       test.order,
+		}
+		m.setItems()
+
+		var got []string
+		for _, item := range m.list.Items() {
+			switch i := item.(type) {
+			case groupItem:
+				label := fmt.Sprintf("%s (%d)", i.name, i.count)
+				if i.collapsed {
+					label += " collapsed"
+				}
+				got = append(got, label)
+			case connectionItem:
+				got = append(got, i.Name)
+			}
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s:\n got %q\nwant %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// Estimates off by this factor or more are flagged
+	badEstimateRatio = 10
+	// Sequential scans on tables with at least this many rows are flagged
+	bigTableRows = 10000
+)
+
+type PlanNode struct {
+	NodeType          string      `json:"Node Type"`
+	RelationName      string      `json:"Relation Name"`
+	Alias             string      `json:"Alias"`
+	IndexName         string      `json:"Index Name"`
+	JoinType          string      `json:"Join Type"`
+	StartupCost       float64     `json:"Startup Cost"`
+	TotalCost         float64     `json:"Total Cost"`
+	PlanRows          float64     `json:"Plan Rows"`
+	ActualStartupTime float64     `json:"Actual Startup Time"`
+	ActualTotalTime   float64     `json:"Actual Total Time"`
+	ActualRows        float64     `json:"Actual Rows"`
+	ActualLoops       float64     `json:"Actual Loops"`
+	Filter            string      `json:"Filter"`
+	IndexCond         string      `json:"Index Cond"`
+	JoinFilter        string      `json:"Join Filter"`
+	HashCond          string      `json:"Hash Cond"`
+	RowsRemoved       float64     `json:"Rows Removed by Filter"`
+	SharedHitBlocks   float64     `json:"Shared Hit Blocks"`
+	SharedReadBlocks  float64     `json:"Shared Read Blocks"`
+	Plans             []*PlanNode `json:"Plans"`
+
+	exclusiveTime float64
+	tableRows     float64
+}
+
+type ExplainPlan struct {
+	Plan          *PlanNode `json:"Plan"`
+	PlanningTime  float64   `json:"Planning Time"`
+	ExecutionTime float64   `json:"Execution Time"`
+}
+
+// Explain runs EXPLAIN (FORMAT JSON) on a statement. With analyze the
+// statement is executed inside a transaction that is always rolled back, or
+// under a savepoint when the session already has a transaction open.
+func (s *Session) Explain(sql string, args []any, analyze bool, buffers bool) (ExplainPlan, error) {
+	conn, err := s.postgres()
+	if err != nil {
+		return ExplainPlan{}, err
+	}
+
+	begin, rollback := "BEGIN", "ROLLBACK"
+	if s.TxStatus() != TX_IDLE {
+		begin, rollback = "SAVEPOINT termtable_explain", "ROLLBACK TO SAVEPOINT termtable_explain; RELEASE SAVEPOINT termtable_explain"
+	}
+
+	if err := s.Exec(begin); err != nil {
+		return ExplainPlan{}, err
+	}
+	defer s.Exec(rollback)
+
+	options := []string{"FORMAT JSON"}
+	if analyze {
+		options = append(options, "ANALYZE")
+	}
+	if buffers {
+		options = append(options, "BUFFERS")
+	}
+
+	var output string
+	prefix := fmt.Sprintf("EXPLAIN (%s) ", strings.Join(options, ", "))
+	err = conn.QueryRow(context.Background(), prefix+sql, args...).Scan(&output)
+	if err != nil {
+		// Errors point into the text sent, make them point into the statement
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Position > 0 {
+			pgErr.Position = max(pgErr.Position-int32(len(prefix)), 0)
+		}
+		return ExplainPlan{}, err
+	}
+
+	var plans []ExplainPlan
+	if err := json.Unmarshal([]byte(output), &plans); err != nil {
+		return ExplainPlan{}, err
+	}
+
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return ExplainPlan{}, errors.New("explain returned no plan")
+	}
+	plan := plans[0]
+
+	// Look up the size of scanned tables to flag sequential scans on big ones
+	relations := map[string][]*PlanNode{}
+	var names []string
+	walkPlan(plan.Plan, func(node *PlanNode) {
+		if node.NodeType == "Seq Scan" && node.RelationName != "" {
+			if _, ok := relations[node.RelationName]; !ok {
+				names = append(names, node.RelationName)
+			}
+			relations[node.RelationName] = append(relations[node.RelationName], node)
+		}
+	})
+
+	if len(names) > 0 {
+		rows, err := conn.Query(context.Background(),
+			"SELECT relname, reltuples::float8 FROM pg_class WHERE relname = ANY($1) AND relkind = 'r'", names)
+		if err != nil {
+			return ExplainPlan{}, err
+		}
+
+		for rows.Next() {
+			var name string
+			var tuples float64
+			if err := rows.Scan(&name, &tuples); err != nil {
+				return ExplainPlan{}, err
+			}
+			for _, node := range relations[name] {
+				node.tableRows = tuples
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return ExplainPlan{}, err
+		}
+	}
+
+	walkPlan(plan.Plan, func(node *PlanNode) {
+		node.exclusiveTime = node.ActualTotalTime * math.Max(node.ActualLoops, 1)
+		for _, child := range node.Plans {
+			node.exclusiveTime -= child.ActualTotalTime * math.Max(child.ActualLoops, 1)
+		}
+		node.exclusiveTime = math.Max(node.exclusiveTime, 0)
+	})
+
+	return plan, nil
+}
+
+func walkPlan(node *PlanNode, fn func(*PlanNode)) {
+	fn(node)
+	for _, child := range node.Plans {
+		walkPlan(child, fn)
+	}
+}
+
+func (n *PlanNode) title() string {
+	title := n.NodeType
+	if n.JoinType != "" {
+		title = n.JoinType + " " + title
+	}
+	if n.IndexName != "" {
+		title += " using " + n.IndexName
+	}
+	if n.RelationName != "" {
+		title += " on " + n.RelationName
+		if n.Alias != "" && n.Alias != n.RelationName {
+			title += " " + n.Alias
+		}
+	}
+
+	return title
+}
+
+// estimateRatio is how far the planner's row estimate is from the actual
+// rows, as a factor of at least 1.
+func (n *PlanNode) estimateRatio() float64 {
+	estimated := math.Max(n.PlanRows, 1)
+	actual := math.Max(n.ActualRows, 1)
+
+	return math.Max(estimated, actual) / math.Min(estimated, actual)
+}
+
+type planRow struct {
+	node   *PlanNode
+	prefix string
+}
+
+type ExplainModel struct {
+	session   *Session
+	statement string
+	sql       string
+	args      []any
+	analyze   bool
+	buffers   bool
+	plan      ExplainPlan
+	rows      []planRow
+	collapsed map[*PlanNode]bool
+	slowest   *PlanNode
+	cursor    int
+	offset    int
+	back      bool
+	err       error
+}
+
+func NewExplainModel(session *Session, statement string, sql string, args []any) ExplainModel {
+	m := ExplainModel{
+		session:   session,
+		statement: statement,
+		sql:       sql,
+		args:      args,
+		collapsed: map[*PlanNode]bool{},
+	}
+	m.explain()
+
+	return m
+}
+
+func (m *ExplainModel) explain() {
+	m.plan, m.err = m.session.Explain(m.sql, m.args, m.analyze, m.buffers)
+	m.collapsed = map[*PlanNode]bool{}
+	m.cursor = 0
+	m.offset = 0
+	m.slowest = nil
+	m.rows = nil
+
+	if m.err != nil {
+		return
+	}
+
+	if m.analyze {
+		walkPlan(m.plan.Plan, func(node *PlanNode) {
+			if m.slowest == nil || node.exclusiveTime > m.slowest.exclusiveTime {
+				m.slowest = node
+			}
+		})
+	}
+
+	m.flatten()
+}
+
+// flatten lists the visible nodes of the tree with their branch glyphs.
+func (m *ExplainModel) flatten() {
+	m.rows = nil
+
+	var walk func(node *PlanNode, prefix string, childPrefix string)
+	walk = func(node *PlanNode, prefix string, childPrefix string) {
+		m.rows = append(m.rows, planRow{node: node, prefix: prefix})
+		if m.collapsed[node] {
+			return
+		}
+
+		for i, child := range node.Plans {
+			if i == len(node.Plans)-1 {
+				walk(child, childPrefix+"└─ ", childPrefix+"   ")
+			} else {
+				walk(child, childPrefix+"├─ ", childPrefix+"│  ")
+			}
+		}
+	}
+	walk(m.plan.Plan, "", "")
+
+	m.cursor = min(m.cursor, len(m.rows)-1)
+}
+
+func (m ExplainModel) hotspots(n *PlanNode) []string {
+	var flags []string
+
+	if n == m.slowest && n.exclusiveTime > 0 {
+		flags = append(flags, fmt.Sprintf("slowest %.2f ms", n.exclusiveTime))
+	}
+
+	if m.analyze && n.ActualLoops > 0 {
+		if ratio := n.estimateRatio(); ratio >= badEstimateRatio {
+			flags = append(flags, fmt.Sprintf("estimate off x%.0f", ratio))
+		}
+	}
+
+	if n.NodeType == "Seq Scan" && n.tableRows >= bigTableRows {
+		flags = append(flags, fmt.Sprintf("seq scan on %.0f rows", n.tableRows))
+	}
+
+	return flags
+}
+
+func (m ExplainModel) nodeLine(row planRow) string {
+	n := row.node
+
+	marker := "  "
+	if len(n.Plans) > 0 {
+		marker = "▾ "
+		if m.collapsed[n] {
+			marker = "▸ "
+		}
+	}
+
+	s := fmt.Sprintf("%s%s%s  %s", blurredStyle.Render(row.prefix), marker, n.title(),
+		blurredStyle.Render(fmt.Sprintf("cost=%.2f..%.2f rows=%.0f", n.StartupCost, n.TotalCost, n.PlanRows)))
+
+	if m.analyze {
+		s += fmt.Sprintf("  actual=%.3f..%.3f ms rows=%.0f loops=%.0f excl=%.3f ms",
+			n.ActualStartupTime, n.ActualTotalTime, n.ActualRows, n.ActualLoops, n.exclusiveTime)
+	}
+
+	if flags := m.hotspots(n); len(flags) > 0 {
+		s += "  " + errorStyle.Render(strings.Join(flags, ", "))
+	}
+
+	return s
+}
+
+func (m ExplainModel) detailView(n *PlanNode) string {
+	var details []string
+	for _, detail := range []struct{ label, value string }{
+		{"Filter", n.Filter},
+		{"Index Cond", n.IndexCond},
+		{"Join Filter", n.JoinFilter},
+		{"Hash Cond", n.HashCond},
+	} {
+		if detail.value != "" {
+			details = append(details, fmt.Sprintf("%s: %s", detail.label, highlightSQL(detail.value)))
+		}
+	}
+
+	if n.RowsRemoved > 0 {
+		details = append(details, fmt.Sprintf("Rows removed by filter: %.0f", n.RowsRemoved))
+	}
+
+	if m.buffers {
+		details = append(details, fmt.Sprintf("Buffers: shared hit=%.0f read=%.0f", n.SharedHitBlocks, n.SharedReadBlocks))
+	}
+
+	return strings.Join(details, "\n")
+}
+
+func (m ExplainModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ExplainModel) Update(msg tea.Msg) (ExplainModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, explainKeys.Back):
+		m.back = true
+
+	case key.Matches(keyMsg, explainKeys.Analyze):
+		m.analyze = !m.analyze
+		m.explain()
+
+	case key.Matches(keyMsg, explainKeys.Buffers):
+		m.buffers = !m.buffers
+		m.explain()
+
+	case key.Matches(keyMsg, explainKeys.Up):
+		m.cursor = max(m.cursor-1, 0)
+
+	case key.Matches(keyMsg, explainKeys.Down):
+		m.cursor = min(m.cursor+1, len(m.rows)-1)
+
+	case key.Matches(keyMsg, explainKeys.Toggle, explainKeys.Collapse, explainKeys.Expand):
+		if m.cursor < 0 || m.cursor >= len(m.rows) {
+			break
+		}
+
+		node := m.rows[m.cursor].node
+		switch {
+		case key.Matches(keyMsg, explainKeys.Collapse):
+			m.collapsed[node] = true
+		case key.Matches(keyMsg, explainKeys.Expand):
+			delete(m.collapsed, node)
+		default:
+			m.collapsed[node] = !m.collapsed[node]
+		}
+		m.flatten()
+	}
+
+	// Keep the cursor within the visible window of the tree
+	treeHeight := max(height/2, 5)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	} else if m.cursor >= m.offset+treeHeight {
+		m.offset = m.cursor - treeHeight + 1
+	}
+
+	return m, nil
+}
+
+func (m ExplainModel) View() string {
+	var b strings.Builder
+
+	options := "EXPLAIN"
+	if m.analyze {
+		options += " ANALYZE"
+	}
+	if m.buffers {
+		options += " BUFFERS"
+	}
+	fmt.Fprintf(&b, "%s %s\n\n", options, highlightSQL(strings.Join(strings.Fields(m.statement), " ")))
+
+	if m.err != nil {
+		b.WriteString(errorView(m.err, m.sql))
+	} else {
+		treeHeight := max(height/2, 5)
+		for i := m.offset; i < len(m.rows) && i < m.offset+treeHeight; i++ {
+			line := m.nodeLine(m.rows[i])
+			if i == m.cursor {
+				line = selectedItemStyle.Render("> ") + line
+			} else {
+				line = "  " + line
+			}
+			b.WriteString(line + "\n")
+		}
+
+		if m.analyze {
+			fmt.Fprintf(&b, "\nPlanning %.3f ms, execution %.3f ms", m.plan.PlanningTime, m.plan.ExecutionTime)
+		}
+
+		if m.cursor >= 0 && m.cursor < len(m.rows) {
+			if details := m.detailView(m.rows[m.cursor].node); details != "" {
+				b.WriteString("\n\n" + details)
+			}
+		}
+	}
+
+	b.WriteString(helpStyle.Render("\n\n" + keyHelp(explainKeys.Up, explainKeys.Down, explainKeys.Toggle, explainKeys.Analyze,
+		explainKeys.Buffers, explainKeys.Back)))
+
+	return b.String()
+}
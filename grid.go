@@ -0,0 +1,355 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// GridModel browses a Table on its own, with sorting, searching, inspecting
+// a row and exporting what is shown. It needs no connection.
+type GridModel struct {
+	data       Table
+	rows       [][]string
+	table      table.Model
+	column     int
+	sortColumn int
+	sortDesc   bool
+	search     textinput.Model
+	searching  bool
+	inspect    viewport.Model
+	inspecting bool
+	path       textinput.Model
+	exporting  bool
+	status     string
+	err        error
+	back       bool
+}
+
+func NewGridModel(data Table) GridModel {
+	search := textinput.New()
+	search.Cursor.Style = cursorStyle
+	search.Prompt = "/"
+
+	path := textinput.New()
+	path.Cursor.Style = cursorStyle
+	path.Prompt = "Export to: "
+	path.Placeholder = "rows.csv or rows.json"
+
+	m := GridModel{
+		data:       data,
+		table:      newResultTable(data),
+		sortColumn: -1,
+		search:     search,
+		path:       path,
+	}
+	m.refresh()
+
+	return m
+}
+
+// compareCells orders numbers by value and everything else as text.
+func compareCells(a string, b string) int {
+	x, errX := strconv.ParseFloat(a, 64)
+	y, errY := strconv.ParseFloat(b, 64)
+	if errX == nil && errY == nil {
+		return cmp.Compare(x, y)
+	}
+
+	return strings.Compare(a, b)
+}
+
+func (m GridModel) columns() []table.Column {
+	columns := make([]table.Column, len(m.data.fields))
+	for i, field := range m.data.fields {
+		title := field
+		if i == m.sortColumn {
+			if m.sortDesc {
+				title += " ▼"
+			} else {
+				title += " ▲"
+			}
+		}
+		if i == m.column {
+			title = "[" + title + "]"
+		}
+
+		columns[i] = table.Column{Title: title, Width: width / 2 / len(m.data.fields)}
+	}
+
+	return columns
+}
+
+// refresh filters the rows on the search, sorts them and shows the result,
+// keeping the cursor within the rows left.
+func (m *GridModel) refresh() {
+	query := strings.ToLower(m.search.Value())
+
+	m.rows = nil
+	for _, row := range m.data.values {
+		if query == "" || slices.ContainsFunc(row, func(cell string) bool {
+			return strings.Contains(strings.ToLower(cell), query)
+		}) {
+			m.rows = append(m.rows, row)
+		}
+	}
+
+	if m.sortColumn >= 0 {
+		slices.SortStableFunc(m.rows, func(a []string, b []string) int {
+			if m.sortDesc {
+				return compareCells(b[m.sortColumn], a[m.sortColumn])
+			}
+			return compareCells(a[m.sortColumn], b[m.sortColumn])
+		})
+	}
+
+	rows := make([]table.Row, len(m.rows))
+	for i, row := range m.rows {
+		rows[i] = table.Row(row)
+	}
+
+	if len(m.data.fields) > 0 {
+		m.table.SetColumns(m.columns())
+	}
+	m.table.SetRows(rows)
+	if m.table.Cursor() >= len(rows) {
+		m.table.SetCursor(max(len(rows)-1, 0))
+	}
+}
+
+// inspectRow lays the selected row out one field per line so long values
+// can be read in full.
+func (m *GridModel) inspectRow() {
+	cursor := m.table.Cursor()
+	if cursor >= len(m.rows) {
+		return
+	}
+
+	nameWidth := 0
+	for _, field := range m.data.fields {
+		nameWidth = max(nameWidth, lipgloss.Width(field))
+	}
+
+	var b strings.Builder
+	for i, field := range m.data.fields {
+		name := focusedStyle.Render(field + strings.Repeat(" ", nameWidth-lipgloss.Width(field)))
+		value := strings.ReplaceAll(m.rows[cursor][i], "\n", "\n"+strings.Repeat(" ", nameWidth+2))
+		fmt.Fprintf(&b, "%s  %s\n", name, value)
+	}
+
+	m.inspect = viewport.New(width, height/2)
+	m.inspect.SetContent(b.String())
+	m.inspecting = true
+}
+
+// export writes the rows as they are shown, filtered and sorted, as json when
+// the file ends in .json and csv otherwise.
+func (m GridModel) export(path string) error {
+	result := ResultSet{fields: m.data.fields, kinds: make([]ValueKind, len(m.data.fields))}
+	for i := range result.kinds {
+		result.kinds[i] = VALUE_TEXT
+	}
+	for _, row := range m.rows {
+		values := make([]*string, len(row))
+		for i := range row {
+			values[i] = &row[i]
+		}
+		result.values = append(result.values, values)
+	}
+
+	f, err := os.Create(expandHome(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return result.writeJSON(f)
+	}
+	return result.writeCSV(f)
+}
+
+func (m GridModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m GridModel) Update(msg tea.Msg) (GridModel, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		width = msg.Width
+		height = msg.Height
+		m.table.SetWidth(width / 2)
+		m.table.SetHeight(height / 2)
+		m.refresh()
+		return m, nil
+	}
+
+	switch {
+	case m.inspecting:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			if key.Matches(msg, pagerKeys.Close) {
+				m.inspecting = false
+				return m, nil
+			}
+		}
+
+		m.inspect, cmd = m.inspect.Update(msg)
+		return m, cmd
+
+	case m.searching:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch {
+			case key.Matches(msg, promptKeys.Submit):
+				m.searching = false
+				m.search.Blur()
+				return m, nil
+
+			case key.Matches(msg, promptKeys.Cancel):
+				m.searching = false
+				m.search.Blur()
+				m.search.SetValue("")
+				m.refresh()
+				return m, nil
+			}
+		}
+
+		m.search, cmd = m.search.Update(msg)
+		m.refresh()
+		return m, cmd
+
+	case m.exporting:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch {
+			case key.Matches(msg, promptKeys.Submit):
+				path := m.path.Value()
+				if path == "" {
+					return m, nil
+				}
+
+				m.exporting = false
+				m.path.Blur()
+				if m.err = m.export(path); m.err == nil {
+					m.status = fmt.Sprintf("Exported %d rows to %s", len(m.rows), path)
+				}
+				return m, nil
+
+			case key.Matches(msg, promptKeys.Cancel):
+				m.exporting = false
+				m.path.Blur()
+				return m, nil
+			}
+		}
+
+		m.path, cmd = m.path.Update(msg)
+		return m, cmd
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, gridKeys.Quit):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, gridKeys.Back):
+			if m.search.Value() != "" {
+				m.search.SetValue("")
+				m.refresh()
+				return m, nil
+			}
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, gridKeys.Left):
+			if m.column > 0 {
+				m.column--
+				m.refresh()
+			}
+			return m, nil
+
+		case key.Matches(msg, gridKeys.Right):
+			if m.column < len(m.data.fields)-1 {
+				m.column++
+				m.refresh()
+			}
+			return m, nil
+
+		// Ascending, descending, then back to the order of the file
+		case key.Matches(msg, gridKeys.Sort):
+			switch {
+			case m.sortColumn != m.column:
+				m.sortColumn = m.column
+				m.sortDesc = false
+			case !m.sortDesc:
+				m.sortDesc = true
+			default:
+				m.sortColumn = -1
+			}
+			m.refresh()
+			return m, nil
+
+		case key.Matches(msg, gridKeys.Search):
+			m.searching = true
+			return m, m.search.Focus()
+
+		case key.Matches(msg, gridKeys.Inspect):
+			m.inspectRow()
+			return m, nil
+
+		case key.Matches(msg, gridKeys.Export):
+			m.exporting = true
+			m.status = ""
+			m.err = nil
+			return m, m.path.Focus()
+		}
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m GridModel) View() string {
+	if m.inspecting {
+		return m.inspect.View() + helpStyle.Render("\n"+keyHelp(m.inspect.KeyMap.Up, m.inspect.KeyMap.Down, pagerKeys.Close))
+	}
+
+	s := m.table.View() + "\n"
+
+	count := fmt.Sprintf("%d rows", len(m.rows))
+	if len(m.rows) != len(m.data.values) {
+		count = fmt.Sprintf("%d of %d rows", len(m.rows), len(m.data.values))
+	}
+	s += blurredStyle.Render(count)
+
+	switch {
+	case m.searching:
+		s += "\n" + m.search.View()
+	case m.search.Value() != "":
+		s += "\n" + blurredStyle.Render("/"+m.search.Value())
+	}
+
+	if m.exporting {
+		s += "\n" + m.path.View()
+	}
+
+	if m.status != "" {
+		s += "\n" + successStyle.Render(m.status)
+	}
+	if m.err != nil {
+		s += "\n" + errorStyle.Render(m.err.Error())
+	}
+
+	return s + helpStyle.Render("\n"+keyHelp(gridKeys.Left, gridKeys.Right, gridKeys.Sort, gridKeys.Search, gridKeys.Inspect,
+		gridKeys.Export, gridKeys.Quit))
+}
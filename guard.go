@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dangerousStatement returns why a statement needs confirmation before it
+// runs, or "" when it is safe. DROP and TRUNCATE always do, DELETE and UPDATE
+// only without a WHERE clause of their own.
+func dangerousStatement(sql string) string {
+	depth := 0
+	verb := ""
+	with := false
+
+	for _, token := range significantTokens(sql) {
+		if token.kind == TOKEN_OPERATOR {
+			switch token.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			continue
+		}
+
+		if token.kind != TOKEN_WORD || depth > 0 {
+			continue
+		}
+		word := strings.ToUpper(token.text)
+
+		if verb == "" {
+			switch word {
+			case "DROP", "TRUNCATE":
+				return word
+			case "DELETE", "UPDATE":
+				verb = word
+				continue
+			case "WITH":
+				with = true
+				continue
+			}
+			// Look past the common table expressions for the statement
+			if !with {
+				return ""
+			}
+			continue
+		}
+
+		if word == "WHERE" {
+			return ""
+		}
+	}
+
+	if verb != "" {
+		return verb + " without WHERE"
+	}
+
+	return ""
+}
+
+// dangerousStatements lists the statements of a script that need confirmation.
+func dangerousStatements(statements []scriptStatement) []string {
+	var reasons []string
+	for i, statement := range statements {
+		if reason := dangerousStatement(statement.statement); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("statement %d: %s", i+1, reason))
+		}
+	}
+
+	return reasons
+}
+
+// ConfirmModel asks to type the connection name before running dangerous
+// statements.
+type ConfirmModel struct {
+	name      string
+	reasons   []string
+	input     textinput.Model
+	confirmed bool
+	back      bool
+	mismatch  bool
+}
+
+func NewConfirmModel(name string, reasons []string) (ConfirmModel, tea.Cmd) {
+	t := textinput.New()
+	t.Cursor.Style = cursorStyle
+	t.Prompt = "Connection name: "
+	t.PromptStyle = focusedItemStyle
+	t.TextStyle = focusedItemStyle
+
+	m := ConfirmModel{
+		name:    name,
+		reasons: reasons,
+		input:   t,
+	}
+
+	return m, m.input.Focus()
+}
+
+func (m ConfirmModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m ConfirmModel) Update(msg tea.Msg) (ConfirmModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, promptKeys.Cancel):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, promptKeys.Submit):
+			if m.input.Value() == m.name {
+				m.confirmed = true
+			} else {
+				m.mismatch = true
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	value := m.input.Value()
+	m.input, cmd = m.input.Update(msg)
+	// Keep the error until the name is edited, blinking the cursor is not
+	if m.input.Value() != value {
+		m.mismatch = false
+	}
+	return m, cmd
+}
+
+func (m ConfirmModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(errorStyle.Render("Dangerous statement") + "\n\n")
+	for _, reason := range m.reasons {
+		b.WriteString("  " + reason + "\n")
+	}
+
+	fmt.Fprintf(&b, "\nType %s to run anyway\n\n", focusedStyle.Render(m.name))
+	b.WriteString(m.input.View())
+
+	if m.mismatch {
+		b.WriteString("\n" + errorStyle.Render("Name does not match"))
+	}
+
+	b.WriteString(helpStyle.Render("\n\n" + keyHelp(withHelp(promptKeys.Submit, "run"), promptKeys.Cancel)))
+
+	return b.String()
+}
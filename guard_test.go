@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDangerousStatement(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "select * from users", want: ""},
+		{sql: "drop table users", want: "DROP"},
+		{sql: "  TRUNCATE users", want: "TRUNCATE"},
+		{sql: "/* cleanup */ delete from users", want: "DELETE without WHERE"},
+		{sql: "delete from users where id = 1", want: ""},
+		{sql: "update users set active = false", want: "UPDATE without WHERE"},
+		{sql: "update users set active = (select true from t where x)", want: "UPDATE without WHERE"},
+		{sql: "update users set active = false where id in (select id from t)", want: ""},
+		{sql: "delete from users -- where id = 1", want: "DELETE without WHERE"},
+		{sql: "delete from users where 'x' = 'where'", want: ""},
+		{sql: "delete from \"where\"", want: "DELETE without WHERE"},
+		{
+			sql:  "with old as (delete from logs where ts < now() returning id) delete from users",
+			want: "DELETE without WHERE",
+		},
+		{
+			sql:  "with old as (select id from users where inactive) delete from users where id in (select id from old)",
+			want: "",
+		},
+		{sql: "with t as (select 1) select * from t", want: ""},
+		{sql: "insert into users select * from staging", want: ""},
+		{sql: "", want: ""},
+	}
+
+	for _, test := range tests {
+		if got := dangerousStatement(test.sql); got != test.want {
+			t.Errorf("dangerousStatement(%q) = %q, want %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestConfirmModelMismatch(t *testing.T) {
+	m, _ := NewConfirmModel("prod", []string{"statement 1: DROP"})
+
+	for _, r := range "prd" {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.mismatch || m.confirmed {
+		t.Fatalf("mismatch = %v, confirmed = %v after a wrong name", m.mismatch, m.confirmed)
+	}
+
+	// The cursor blinking must not hide the error
+	m, _ = m.Update(cursor.BlinkMsg{})
+	if !m.mismatch {
+		t.Error("mismatch cleared by a non-key message")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	if m.mismatch {
+		t.Error("mismatch kept after editing the name")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	operatorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(LIGHT_GREY))
+	editorCursor  = lipgloss.NewStyle().Reverse(true)
+
+	// Set from the theme
+	keywordStyle     lipgloss.Style
+	identifierStyle  lipgloss.Style
+	stringStyle      lipgloss.Style
+	numberStyle      lipgloss.Style
+	commentStyle     lipgloss.Style
+	placeholderStyle lipgloss.Style
+)
+
+func tokenStyle(token sqlToken) *lipgloss.Style {
+	switch token.kind {
+	case TOKEN_WORD:
+		if isSQLKeyword(token.text) {
+			return &keywordStyle
+		}
+		return &identifierStyle
+	case TOKEN_QUOTED_IDENT:
+		return &identifierStyle
+	case TOKEN_STRING:
+		return &stringStyle
+	case TOKEN_NUMBER:
+		return &numberStyle
+	case TOKEN_COMMENT:
+		return &commentStyle
+	case TOKEN_PARAM:
+		return &placeholderStyle
+	case TOKEN_OPERATOR:
+		return &operatorStyle
+	}
+
+	return &noStyle
+}
+
+// renderLines renders text line by line so multi-line tokens are not padded
+// into a block by lipgloss.
+func renderLines(style lipgloss.Style, text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = style.Render(line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// highlightSQL colours the keywords, identifiers, strings, numbers, comments
+// and operators of a statement.
+func highlightSQL(sql string) string {
+	var b strings.Builder
+
+	for _, token := range tokenizeSQL(sql) {
+		if token.kind == TOKEN_WHITESPACE {
+			b.WriteString(token.text)
+			continue
+		}
+		b.WriteString(renderLines(*tokenStyle(token), token.text))
+	}
+
+	return b.String()
+}
+
+type styledRune struct {
+	r     rune
+	style *lipgloss.Style
+}
+
+// highlightedLines splits a highlighted statement into lines of runes so it
+// can be wrapped and have a cursor drawn on it.
+func highlightedLines(sql string) [][]styledRune {
+	lines := [][]styledRune{{}}
+
+	for _, token := range tokenizeSQL(sql) {
+		style := tokenStyle(token)
+		for _, r := range token.text {
+			if r == '\n' {
+				lines = append(lines, []styledRune{})
+				continue
+			}
+			if r == '\t' {
+				r = ' '
+			}
+			lines[len(lines)-1] = append(lines[len(lines)-1], styledRune{r: r, style: style})
+		}
+	}
+
+	return lines
+}
+
+// renderStyledRunes renders runs of runes sharing a style together.
+func renderStyledRunes(runes []styledRune) string {
+	var b strings.Builder
+
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && runes[end].style == runes[start].style {
+			end++
+		}
+
+		var s strings.Builder
+		for _, r := range runes[start:end] {
+			s.WriteRune(r.r)
+		}
+		b.WriteString(runes[start].style.Render(s.String()))
+
+		start = end
+	}
+
+	return b.String()
+}
+
+// highlightedEditorView draws a statement highlighted with the cursor at row
+// and col, soft wrapping lines to width and scrolling to keep the cursor
+// within height lines.
+func highlightedEditorView(sql string, row int, col int, width int, height int) string {
+	var display []string
+	cursorLine := 0
+	numberWidth := 3
+	textWidth := max(width-numberWidth-1, 1)
+
+	for i, line := range highlightedLines(sql) {
+		if i == row {
+			if col < len(line) {
+				line[col].style = &editorCursor
+			} else {
+				line = append(line, styledRune{r: ' ', style: &editorCursor})
+			}
+		}
+
+		for start := 0; start == 0 || start < len(line); start += textWidth {
+			number := strings.Repeat(" ", numberWidth)
+			if start == 0 {
+				number = fmt.Sprintf("%*d", numberWidth, i+1)
+			}
+
+			if i == row && col >= start && col < start+textWidth {
+				cursorLine = len(display)
+			}
+
+			chunk := line[start:min(start+textWidth, len(line))]
+			display = append(display, blurredStyle.Render(number)+" "+renderStyledRunes(chunk))
+		}
+	}
+
+	first := max(0, cursorLine-height+1)
+	last := min(len(display), first+height)
+	view := display[first:last]
+	for len(view) < height {
+		view = append(view, "")
+	}
+
+	return strings.Join(view, "\n")
+}
+
+// errorView renders an error, pointing at the position Postgres reports for
+// syntax errors within the statement that was run.
+func errorView(err error, statement string) string {
+	s := errorStyle.Render(err.Error())
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Position <= 0 || statement == "" {
+		return s
+	}
+
+	// Position is a 1 based character offset
+	runes := []rune(statement)
+	offset := min(int(pgErr.Position)-1, len(runes))
+
+	lineStart := offset
+	for lineStart > 0 && runes[lineStart-1] != '\n' {
+		lineStart--
+	}
+
+	lineEnd := offset
+	for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+		lineEnd++
+	}
+
+	return fmt.Sprintf("%s\n%s\n%s%s",
+		s,
+		highlightSQL(string(runes[lineStart:lineEnd])),
+		strings.Repeat(" ", offset-lineStart),
+		errorStyle.Render("^"))
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	HISTORY_BUCKET_NAME = "query_history"
+
+	maxHistoryEntries = 1000
+)
+
+type HistoryEntry struct {
+	Statement string        `json:"statement"`
+	Time      time.Time     `json:"time"`
+	Duration  time.Duration `json:"duration"`
+	Rows      int64         `json:"rows"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// AddHistoryEntry records a statement in the history bucket of the connection,
+// dropping the oldest entries once maxHistoryEntries is reached.
+func AddHistoryEntry(connName string, entry HistoryEntry) error {
+	db, err := openLocalDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		history, err := tx.CreateBucketIfNotExists([]byte(HISTORY_BUCKET_NAME))
+		if err != nil {
+			return err
+		}
+
+		b, err := history.CreateBucketIfNotExists([]byte(connName))
+		if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, id)
+
+		if err := b.Put(key, value); err != nil {
+			return err
+		}
+
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k)+maxHistoryEntries <= id; k, _ = c.First() {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListHistory returns the recorded statements of a connection, newest first.
+func ListHistory(connName string) ([]HistoryEntry, error) {
+	db, err := openLocalDb()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var entries []HistoryEntry
+
+	err = db.View(func(tx *bolt.Tx) error {
+		history := tx.Bucket([]byte(HISTORY_BUCKET_NAME))
+		if history == nil {
+			return nil
+		}
+
+		b := history.Bucket([]byte(connName))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var entry HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+
+		return nil
+	})
+
+	return entries, err
+}
+
+type historyItem HistoryEntry
+
+func (i historyItem) FilterValue() string { return i.Statement }
+
+type historyItemDelegate struct{}
+
+func (d historyItemDelegate) Height() int                             { return 1 }
+func (d historyItemDelegate) Spacing() int                            { return 0 }
+func (d historyItemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d historyItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(historyItem)
+	if !ok {
+		return
+	}
+
+	statement := []rune(strings.Join(strings.Fields(i.Statement), " "))
+	if maxLen := max(m.Width()-40, 20); len(statement) > maxLen {
+		statement = append(statement[:maxLen-1], '…')
+	}
+
+	status := successStyle.Render(fmt.Sprintf("%6d rows", i.Rows))
+	if i.Error != "" {
+		status = errorStyle.Render(fmt.Sprintf("%11s", "error"))
+	}
+
+	str := fmt.Sprintf("%s %8s %s  %s",
+		blurredStyle.Render(i.Time.Local().Format("01-02 15:04:05")),
+		i.Duration.Round(time.Millisecond),
+		status,
+		highlightSQL(string(statement)))
+
+	if index == m.Index() {
+		str = selectedItemStyle.Render("> ") + str
+	} else {
+		str = "  " + str
+	}
+
+	fmt.Fprint(w, str)
+}
+
+type HistoryModel struct {
+	list     list.Model
+	selected *HistoryEntry
+	rerun    bool
+	back     bool
+	err      error
+}
+
+func NewHistoryModel(connName string) HistoryModel {
+	m := HistoryModel{}
+
+	entries, err := ListHistory(connName)
+	m.err = err
+
+	items := make([]list.Item, len(entries))
+	for i, entry := range entries {
+		items[i] = historyItem(entry)
+	}
+
+	l := list.New(items, historyItemDelegate{}, width, cfg.PageSize)
+	l.Title = "History"
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	m.list = l
+
+	return m
+}
+
+func (m HistoryModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m HistoryModel) Update(msg tea.Msg) (HistoryModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.list.FilterState() != list.Filtering {
+		switch {
+		case key.Matches(msg, historyKeys.Quit):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, historyKeys.Back):
+			if m.list.FilterState() == list.Unfiltered {
+				m.back = true
+				return m, nil
+			}
+
+		case key.Matches(msg, historyKeys.Load, historyKeys.Run):
+			if i, ok := m.list.SelectedItem().(historyItem); ok {
+				entry := HistoryEntry(i)
+				m.selected = &entry
+				m.rerun = key.Matches(msg, historyKeys.Run)
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m HistoryModel) View() string {
+	s := m.list.View()
+
+	if m.err != nil {
+		s += "\n" + errorStyle.Render(m.err.Error())
+	}
+
+	return s + helpStyle.Render("\n"+keyHelp(historyKeys.Load, historyKeys.Run, withHelp(m.list.KeyMap.Filter, "search"), historyKeys.Back))
+}
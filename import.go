@@ -0,0 +1,668 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ImportStep string
+
+const (
+	IMPORT_FILE    ImportStep = "IMPORT_FILE"
+	IMPORT_PREVIEW ImportStep = "IMPORT_PREVIEW"
+	IMPORT_MAPPING ImportStep = "IMPORT_MAPPING"
+	IMPORT_LOADING ImportStep = "IMPORT_LOADING"
+	IMPORT_DONE    ImportStep = "IMPORT_DONE"
+)
+
+const (
+	importPreviewRows   = 10
+	importValidateRows  = 100
+	importProgressEvery = 500
+	importShownRejected = 10
+
+	// Mapping value for table columns that are not loaded from the file
+	skipColumn = -1
+)
+
+var importDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// importFile holds the parsed contents of a CSV or NDJSON file. A nil value
+// in a record is loaded as NULL.
+type importFile struct {
+	header  []string
+	records [][]any
+}
+
+// emptyField is an empty CSV field. CSV cannot tell an empty string from a
+// missing value, so it is loaded as NULL where the column allows it and as an
+// empty string where it does not.
+type emptyField struct{}
+
+type rejectedRow struct {
+	row    int
+	reason string
+}
+
+type importProgressMsg struct {
+	rows int
+}
+
+type importDoneMsg struct {
+	copied   int64
+	rejected []rejectedRow
+	err      error
+}
+
+type ImportModel struct {
+	step      ImportStep
+	params    Connection
+	schema    string
+	table     string
+	columns   []Column
+	path      textinput.Model
+	file      importFile
+	preview   table.Model
+	mapping   []int
+	cursor    int
+	progress  chan tea.Msg
+	processed int
+	result    importDoneMsg
+	err       error
+	back      bool
+}
+
+func NewImportModel(params Connection, schema string, tableName string) ImportModel {
+	path := textinput.New()
+	path.Cursor.Style = cursorStyle
+	path.Placeholder = "Path to .csv or .ndjson file"
+	path.PromptStyle = focusedItemStyle
+	path.TextStyle = focusedItemStyle
+	path.Width = width / 2
+	path.Focus()
+
+	m := ImportModel{
+		step:   IMPORT_FILE,
+		params: params,
+		schema: schema,
+		table:  tableName,
+		path:   path,
+	}
+
+	m.columns, m.err = params.GetColumns(schema, tableName)
+
+	return m
+}
+
+func readImportFile(path string) (importFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return importFile{}, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".ndjson", ".jsonl":
+		return readNDJSON(f)
+	default:
+		return readCSV(f)
+	}
+}
+
+// readCSV expects a header row. Empty fields are kept as emptyField and
+// missing trailing fields are treated as NULL.
+func readCSV(r io.Reader) (importFile, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return importFile{}, fmt.Errorf("could not read csv header: %w", err)
+	}
+
+	file := importFile{header: header}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return importFile{}, err
+		}
+
+		record := make([]any, len(header))
+		for i := range record {
+			switch {
+			case i >= len(fields):
+			case fields[i] == "":
+				record[i] = emptyField{}
+			default:
+				record[i] = fields[i]
+			}
+		}
+		file.records = append(file.records, record)
+	}
+
+	return file, nil
+}
+
+// readNDJSON reads one JSON object per line, or a JSON array of objects. The
+// header is the sorted union of all keys, missing keys are treated as NULL.
+func readNDJSON(r io.Reader) (importFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return importFile{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	array := bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+	if array {
+		if _, err := decoder.Token(); err != nil {
+			return importFile{}, err
+		}
+	}
+
+	var objects []map[string]any
+	keys := map[string]bool{}
+	for {
+		if array && !decoder.More() {
+			break
+		}
+
+		var object map[string]any
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return importFile{}, fmt.Errorf("invalid json on object %d: %w", len(objects)+1, err)
+		}
+
+		for k := range object {
+			keys[k] = true
+		}
+		objects = append(objects, object)
+	}
+
+	file := importFile{}
+	for k := range keys {
+		file.header = append(file.header, k)
+	}
+	sort.Strings(file.header)
+
+	for _, object := range objects {
+		record := make([]any, len(file.header))
+		for i, k := range file.header {
+			switch value := object[k].(type) {
+			case nil:
+			case string:
+				record[i] = value
+			case json.Number:
+				record[i] = value.String()
+			case bool:
+				record[i] = strconv.FormatBool(value)
+			default:
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return importFile{}, err
+				}
+				record[i] = string(encoded)
+			}
+		}
+		file.records = append(file.records, record)
+	}
+
+	return file, nil
+}
+
+// convertImportValue validates a file value against the catalog type of the
+// column it is mapped to and converts it to a value pgx can encode.
+func convertImportValue(value any, column Column) (any, error) {
+	if _, ok := value.(emptyField); ok {
+		value = nil
+		if !column.Nullable {
+			value = ""
+		}
+	}
+
+	if value == nil {
+		if !column.Nullable {
+			return nil, fmt.Errorf("%s: null value in non-nullable column", column.Name)
+		}
+		return nil, nil
+	}
+
+	s := value.(string)
+	invalid := func() error {
+		return fmt.Errorf("%s: invalid %s %q", column.Name, column.DataType, s)
+	}
+
+	switch column.DataType {
+	case "smallint", "integer", "bigint":
+		v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+
+	case "real", "double precision":
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+
+	case "numeric":
+		var v pgtype.Numeric
+		if err := v.Scan(strings.TrimSpace(s)); err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+
+	case "boolean":
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "y", "yes", "on":
+			return true, nil
+		case "n", "no", "off":
+			return false, nil
+		}
+		v, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+
+	case "date", "timestamp without time zone", "timestamp with time zone":
+		for _, layout := range importDateLayouts {
+			if v, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+				return v, nil
+			}
+		}
+		return nil, invalid()
+
+	case "uuid":
+		var v pgtype.UUID
+		if err := v.Scan(strings.TrimSpace(s)); err != nil {
+			return nil, invalid()
+		}
+		return v, nil
+
+	case "json", "jsonb":
+		if !json.Valid([]byte(s)) {
+			return nil, invalid()
+		}
+		return s, nil
+	}
+
+	return s, nil
+}
+
+// importSource feeds mapped and validated records to CopyFrom. Records that
+// fail validation are skipped and collected as rejected rows.
+type importSource struct {
+	records  [][]any
+	columns  []Column
+	mapping  []int
+	index    int
+	values   []any
+	rejected []rejectedRow
+	progress chan<- tea.Msg
+}
+
+func (s *importSource) Next() bool {
+	for s.index < len(s.records) {
+		record := s.records[s.index]
+		s.index++
+
+		if s.index%importProgressEvery == 0 {
+			select {
+			case s.progress <- importProgressMsg{rows: s.index}:
+			default:
+			}
+		}
+
+		values, err := s.convert(record)
+		if err != nil {
+			s.rejected = append(s.rejected, rejectedRow{row: s.index, reason: err.Error()})
+			continue
+		}
+
+		s.values = values
+		return true
+	}
+
+	return false
+}
+
+func (s *importSource) convert(record []any) ([]any, error) {
+	var values []any
+	for i, fileIndex := range s.mapping {
+		if fileIndex == skipColumn {
+			continue
+		}
+
+		value, err := convertImportValue(record[fileIndex], s.columns[i])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+func (s *importSource) Values() ([]any, error) {
+	return s.values, nil
+}
+
+func (s *importSource) Err() error {
+	return nil
+}
+
+func runImport(params Connection, schema string, tableName string, columnNames []string, src *importSource, progress chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		defer close(progress)
+
+		start := time.Now()
+		copied, err := params.CopyFrom(schema, tableName, columnNames, src)
+
+		entry := HistoryEntry{
+			Statement: fmt.Sprintf("COPY %s (%s) FROM STDIN", pgx.Identifier{schema, tableName}.Sanitize(), strings.Join(columnNames, ", ")),
+			Time:      start,
+			Duration:  time.Since(start),
+			Rows:      copied,
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		AddHistoryEntry(params.Name, entry)
+
+		return importDoneMsg{copied: copied, rejected: src.rejected, err: err}
+	}
+}
+
+func waitForImport(progress chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-progress
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (m *ImportModel) loadFile() {
+	file, err := readImportFile(strings.TrimSpace(m.path.Value()))
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	if len(file.header) == 0 {
+		m.err = errors.New("file has no columns")
+		return
+	}
+
+	m.file = file
+	m.err = nil
+
+	columns := make([]table.Column, len(file.header))
+	for i, field := range file.header {
+		columns[i] = table.Column{Title: field, Width: max(width/2/len(file.header), 8)}
+	}
+
+	var rows []table.Row
+	for i := 0; i < len(file.records) && i < importPreviewRows; i++ {
+		row := make(table.Row, len(file.header))
+		for j, value := range file.records[i] {
+			switch value := value.(type) {
+			case nil:
+				row[j] = "NULL"
+			case string:
+				row[j] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	m.preview = table.New(
+		table.WithColumns(columns),
+		table.WithRows(rows),
+		table.WithHeight(importPreviewRows),
+	)
+
+	// Map table columns to file columns with the same name
+	m.mapping = make([]int, len(m.columns))
+	for i, column := range m.columns {
+		m.mapping[i] = skipColumn
+		for j, field := range file.header {
+			if strings.EqualFold(strings.TrimSpace(field), column.Name) {
+				m.mapping[i] = j
+				break
+			}
+		}
+	}
+
+	m.step = IMPORT_PREVIEW
+}
+
+// invalidCount returns how many of the first rows of the file fail validation
+// for a mapped table column.
+func (m ImportModel) invalidCount(column int) int {
+	count := 0
+	fileIndex := m.mapping[column]
+	for i := 0; i < len(m.file.records) && i < importValidateRows; i++ {
+		if _, err := convertImportValue(m.file.records[i][fileIndex], m.columns[column]); err != nil {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (m ImportModel) startImport() (ImportModel, tea.Cmd) {
+	var columnNames []string
+	for i, fileIndex := range m.mapping {
+		if fileIndex != skipColumn {
+			columnNames = append(columnNames, m.columns[i].Name)
+		}
+	}
+
+	if len(columnNames) == 0 {
+		m.err = errors.New("map at least one column")
+		return m, nil
+	}
+
+	m.err = nil
+	m.step = IMPORT_LOADING
+	m.processed = 0
+	m.progress = make(chan tea.Msg, 1)
+
+	src := &importSource{
+		records:  m.file.records,
+		columns:  m.columns,
+		mapping:  m.mapping,
+		progress: m.progress,
+	}
+
+	return m, tea.Batch(
+		runImport(m.params, m.schema, m.table, columnNames, src, m.progress),
+		waitForImport(m.progress),
+	)
+}
+
+func (m ImportModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m ImportModel) Update(msg tea.Msg) (ImportModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case importProgressMsg:
+		m.processed = msg.rows
+		return m, waitForImport(m.progress)
+
+	case importDoneMsg:
+		m.result = msg
+		m.step = IMPORT_DONE
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.step == IMPORT_LOADING {
+			return m, nil
+		}
+
+		switch {
+		case key.Matches(msg, importKeys.Quit):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, importKeys.Back):
+			switch m.step {
+			case IMPORT_MAPPING:
+				m.step = IMPORT_PREVIEW
+			case IMPORT_PREVIEW:
+				m.step = IMPORT_FILE
+			default:
+				m.back = true
+			}
+			m.err = nil
+			return m, nil
+
+		case key.Matches(msg, importKeys.Next):
+			switch m.step {
+			case IMPORT_FILE:
+				if m.columns != nil {
+					m.loadFile()
+				}
+			case IMPORT_PREVIEW:
+				m.step = IMPORT_MAPPING
+			case IMPORT_MAPPING:
+				return m.startImport()
+			case IMPORT_DONE:
+				m.back = true
+			}
+			return m, nil
+		}
+
+		if m.step == IMPORT_MAPPING {
+			switch {
+			case key.Matches(msg, importKeys.Up):
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			case key.Matches(msg, importKeys.Down):
+				if m.cursor < len(m.columns)-1 {
+					m.cursor++
+				}
+			case key.Matches(msg, importKeys.PrevSource, importKeys.NextSource):
+				// Cycle through the file columns, skipColumn sits before the first
+				choices := len(m.file.header) + 1
+				step := 1
+				if key.Matches(msg, importKeys.PrevSource) {
+					step = choices - 1
+				}
+				m.mapping[m.cursor] = (m.mapping[m.cursor]+1+step)%choices - 1
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	switch m.step {
+	case IMPORT_FILE:
+		m.path, cmd = m.path.Update(msg)
+	case IMPORT_PREVIEW:
+		m.preview, cmd = m.preview.Update(msg)
+	}
+
+	return m, cmd
+}
+
+func (m ImportModel) View() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Import into %s\n\n", m.table)
+
+	switch m.step {
+	case IMPORT_FILE:
+		b.WriteString(m.path.View())
+		b.WriteString(helpStyle.Render("\n\n" + keyHelp(withHelp(importKeys.Next, "preview"), withHelp(importKeys.Back, "cancel"))))
+
+	case IMPORT_PREVIEW:
+		b.WriteString(modelStyle.Render(m.preview.View()))
+		fmt.Fprintf(&b, "\n%d rows, %d columns", len(m.file.records), len(m.file.header))
+		b.WriteString(helpStyle.Render("\n\n" + keyHelp(withHelp(importKeys.Next, "map columns"), importKeys.Back)))
+
+	case IMPORT_MAPPING:
+		for i, column := range m.columns {
+			source := blurredStyle.Render("(skip)")
+			if m.mapping[i] != skipColumn {
+				source = m.file.header[m.mapping[i]]
+				if invalid := m.invalidCount(i); invalid > 0 {
+					source += errorStyle.Render(fmt.Sprintf("  %d invalid", invalid))
+				}
+			}
+
+			line := fmt.Sprintf("%-24s %-28s <- %s", column.Name, blurredStyle.Render(column.DataType), source)
+			if i == m.cursor {
+				b.WriteString(selectedItemStyle.Render("> " + line))
+			} else {
+				b.WriteString(itemStyle.Render(line))
+			}
+			b.WriteRune('\n')
+		}
+		b.WriteString(helpStyle.Render("\n" + keyHelp(importKeys.Up, importKeys.Down, importKeys.PrevSource, importKeys.NextSource,
+			withHelp(importKeys.Next, "import"), importKeys.Back)))
+
+	case IMPORT_LOADING:
+		fmt.Fprintf(&b, "Importing... %d / %d rows", m.processed, len(m.file.records))
+
+	case IMPORT_DONE:
+		if m.result.err != nil {
+			b.WriteString(errorStyle.Render(fmt.Sprintf("Import failed, no rows were copied: %s", m.result.err)))
+		} else {
+			b.WriteString(successStyle.Render(fmt.Sprintf("Copied %d rows", m.result.copied)))
+		}
+		fmt.Fprintf(&b, "\n%d rows rejected\n", len(m.result.rejected))
+
+		for i, rejected := range m.result.rejected {
+			if i == importShownRejected {
+				fmt.Fprintf(&b, "... and %d more\n", len(m.result.rejected)-i)
+				break
+			}
+			fmt.Fprintf(&b, "row %d: %s\n", rejected.row, errorStyle.Render(rejected.reason))
+		}
+		b.WriteString(helpStyle.Render("\n" + keyHelp(withHelp(importKeys.Next, "done"))))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n\n" + errorStyle.Render(m.err.Error()))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestConvertImportValue(t *testing.T) {
+	date := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+	timestamp := time.Date(2024, 2, 3, 4, 5, 6, 789000000, time.UTC)
+
+	var numeric pgtype.Numeric
+	if err := numeric.Scan("12.50"); err != nil {
+		t.Fatal(err)
+	}
+	var uuid pgtype.UUID
+	if err := uuid.Scan("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dataType string
+		nullable bool
+		value    any
+		want     any
+		err      bool
+	}{
+		{dataType: "integer", value: " 42 ", want: int64(42)},
+		{dataType: "smallint", value: "-7", want: int64(-7)},
+		{dataType: "bigint", value: "4.2", err: true},
+		{dataType: "real", value: "1.5", want: 1.5},
+		{dataType: "double precision", value: "1e3", want: 1000.0},
+		{dataType: "double precision", value: "many", err: true},
+		{dataType: "numeric", value: "12.50", want: numeric},
+		{dataType: "numeric", value: "12,50", err: true},
+		{dataType: "boolean", value: "yes", want: true},
+		{dataType: "boolean", value: "OFF", want: false},
+		{dataType: "boolean", value: "t", want: true},
+		{dataType: "boolean", value: "maybe", err: true},
+		{dataType: "date", value: "2024-02-03", want: date},
+		{dataType: "timestamp without time zone", value: "2024-02-03 04:05:06.789", want: timestamp},
+		{dataType: "timestamp with time zone", value: "2024-02-03T04:05:06.789Z", want: timestamp},
+		{dataType: "timestamp with time zone", value: "03/02/2024", err: true},
+		{dataType: "uuid", value: "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", want: uuid},
+		{dataType: "uuid", value: "a0eebc99", err: true},
+		{dataType: "jsonb", value: `{"a": [1, 2]}`, want: `{"a": [1, 2]}`},
+		{dataType: "json", value: `{"a":`, err: true},
+		{dataType: "text", value: " as is ", want: " as is "},
+		{dataType: "text", nullable: true, value: nil, want: nil},
+		{dataType: "text", value: nil, err: true},
+		{dataType: "integer", nullable: true, value: emptyField{}, want: nil},
+		{dataType: "text", value: emptyField{}, want: ""},
+		{dataType: "integer", value: emptyField{}, err: true},
+	}
+
+	for _, test := range tests {
+		column := Column{Name: "c", DataType: test.dataType, Nullable: test.nullable}
+
+		got, err := convertImportValue(test.value, column)
+		if test.err {
+			if err == nil {
+				t.Errorf("convertImportValue(%#v, %s) = %#v, want an error", test.value, test.dataType, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("convertImportValue(%#v, %s): %v", test.value, test.dataType, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("convertImportValue(%#v, %s) = %#v, want %#v", test.value, test.dataType, got, test.want)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	file, err := readCSV(strings.NewReader("id,name,note\n1,alice,\n2,\"\",short\n3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := importFile{
+		header: []string{"id", "name", "note"},
+		records: [][]any{
+			{"1", "alice", emptyField{}},
+			{"2", emptyField{}, "short"},
+			{"3", nil, nil},
+		},
+	}
+	if !reflect.DeepEqual(file, want) {
+		t.Errorf("readCSV()\n got %#v\nwant %#v", file, want)
+	}
+
+	if _, err := readCSV(strings.NewReader("")); err == nil {
+		t.Error("readCSV of an empty file succeeded")
+	}
+}
+
+func TestReadNDJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  importFile
+		err   bool
+	}{
+		{
+			name:  "lines with different keys",
+			input: "{\"id\": 1, \"name\": \"alice\"}\n{\"id\": 2, \"active\": true}\n\n",
+			want: importFile{
+				header: []string{"active", "id", "name"},
+				records: [][]any{
+					{nil, "1", "alice"},
+					{"true", "2", nil},
+				},
+			},
+		},
+		{
+			name:  "array",
+			input: " [{\"id\": 1.50, \"tags\": [\"a\", \"b\"]}, {\"id\": null, \"tags\": {\"k\": \"v\"}}]",
+			want: importFile{
+				header: []string{"id", "tags"},
+				records: [][]any{
+					{"1.50", `["a","b"]`},
+					{nil, `{"k":"v"}`},
+				},
+			},
+		},
+		{
+			name:  "empty array",
+			input: "[]",
+			want:  importFile{},
+		},
+		{
+			name:  "invalid line",
+			input: "{\"id\": 1}\n{\"id\":\n",
+			err:   true,
+		},
+		{
+			name:  "not objects",
+			input: "[1, 2]",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		file, err := readNDJSON(strings.NewReader(test.input))
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: readNDJSON() = %#v, want an error", test.name, file)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(file, test.want) {
+			t.Errorf("%s: readNDJSON()\n got %#v\nwant %#v", test.name, file, test.want)
+		}
+	}
+}
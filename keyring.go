@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/zalando/go-keyring"
 	bolt "go.etcd.io/bbolt"
@@ -37,7 +40,17 @@ func getAndOrCreateLocalDb() (string, error) {
 	return localDb, nil
 }
 
-func createBucket(db *bolt.DB) error {
+func openLocalDb() (*bolt.DB, error) {
+	localDb, err := getAndOrCreateLocalDb()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bolt.Open(localDb, 0600, nil)
+}
+
+func createBucket(db *bolt.DB, name string) error {
 	// Start a writable transaction.
 	tx, err := db.Begin(true)
 	if err != nil {
@@ -48,7 +61,7 @@ func createBucket(db *bolt.DB) error {
 	}()
 
 	// Use the transaction...
-	_, err = tx.CreateBucketIfNotExists([]byte(LOCAL_BUCKET_NAME))
+	_, err = tx.CreateBucketIfNotExists([]byte(name))
 	if err != nil {
 		return err
 	}
@@ -74,15 +87,18 @@ func updateLocalDbConn(conn Connection) error {
 	}
 	defer db.Close()
 
-	err = createBucket(db)
+	err = createBucket(db, LOCAL_BUCKET_NAME)
 	if err != nil {
 		return err
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(LOCAL_BUCKET_NAME))
-		err := b.Put([]byte(conn.Name), []byte(fmt.Sprintf("%s:%s:%s", conn.Host, conn.Port, conn.Database)))
-		return err
+		value, err := json.Marshal(conn)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(conn.Name), value)
 	})
 
 	return err
@@ -101,7 +117,7 @@ func deleteLocalDbConn(name string) error {
 	}
 	defer db.Close()
 
-	err = createBucket(db)
+	err = createBucket(db, LOCAL_BUCKET_NAME)
 	if err != nil {
 		return err
 	}
@@ -130,7 +146,7 @@ func listLocalDbConn() (map[string]string, error) {
 	}
 	defer db.Close()
 
-	err = createBucket(db)
+	err = createBucket(db, LOCAL_BUCKET_NAME)
 	if err != nil {
 		return connections, err
 	}
@@ -157,30 +173,45 @@ func createKeyringPassword(username string, password string) string {
 }
 
 func parseKeyringPassword(password string) (string, string, error) {
-	passwordComponents := strings.Split(password, ":")
+	// Only the first colon separates them, passwords may contain more
+	user, pass, found := strings.Cut(password, ":")
 
-	if len(passwordComponents) != 2 {
+	if !found {
 		log.Fatal("Expected saved password to contain 2 components")
 	}
 
-	return passwordComponents[0], passwordComponents[1], nil
+	return user, pass, nil
 }
 
 func SaveConnectionInKeyring(conn Connection) {
+	if err := SaveConnection(conn); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// SaveConnection stores the credentials in the keyring and the rest in the
+// local db. Connections linked to pgpass keep their password there instead.
+func SaveConnection(conn Connection) error {
 	// Save keyring part
-	password := createKeyringPassword(conn.User, conn.Pass)
+	pass := conn.Pass
+	if conn.PgpassLinked {
+		pass = ""
+	}
+	password := createKeyringPassword(conn.User, pass)
 	err := keyring.Set(SERVICE, conn.Name, password)
 
 	if err != nil {
-		log.Fatal("Could not save db credentials in keyring: ", err)
+		return fmt.Errorf("Could not save db credentials in keyring: %w", err)
 	}
 
 	// Save rest to local storage
 	err = updateLocalDbConn(conn)
 
 	if err != nil {
-		log.Fatal("Could not set keyring info into local db: ", err)
+		return fmt.Errorf("Could not set keyring info into local db: %w", err)
 	}
+
+	return nil
 }
 
 func GetConnectionFromKeyring(name string) (string, string, error) {
@@ -203,18 +234,113 @@ func ListConnections() ([]Connection, error) {
 	}
 
 	for k, v := range connections {
-		hostPortDb := strings.Split(v, ":")
-		if len(hostPortDb) != 3 {
+		conn, ok := parseLocalDbConn(k, v)
+		if !ok {
 			continue
 		}
-		conn := Connection{
-			Name:     k,
-			Host:     hostPortDb[0],
-			Port:     hostPortDb[1],
-			Database: hostPortDb[2],
-		}
 		conns = append(conns, conn)
 	}
 
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Name < conns[j].Name
+	})
+
 	return conns, nil
 }
+
+// MarkConnectionUsed records when the connection was last opened.
+func MarkConnectionUsed(conn *Connection) error {
+	conn.LastUsed = time.Now()
+	return updateLocalDbConn(*conn)
+}
+
+// parseLocalDbConn reads a connection saved as JSON, or in the older
+// host:port:database form.
+func parseLocalDbConn(name string, value string) (Connection, bool) {
+	if strings.HasPrefix(value, "{") {
+		var conn Connection
+		if err := json.Unmarshal([]byte(value), &conn); err != nil {
+			return Connection{}, false
+		}
+		conn.Name = name
+		return conn, true
+	}
+
+	hostPortDb := strings.Split(value, ":")
+	if len(hostPortDb) != 3 {
+		return Connection{}, false
+	}
+
+	return Connection{
+		Name:     name,
+		Host:     hostPortDb[0],
+		Port:     hostPortDb[1],
+		Database: hostPortDb[2],
+	}, true
+}
+
+// credentials reads the user and password of a saved connection from the
+// keyring.
+func credentials(name string) (string, string, error) {
+	password, err := keyring.Get(SERVICE, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	user, pass, found := strings.Cut(password, ":")
+	if !found {
+		return "", "", errors.New("Expected saved password to contain 2 components")
+	}
+
+	return user, pass, nil
+}
+
+// withCredentials fills in the user and password of a saved connection.
+// Connections linked to pgpass take the password from there when it has one,
+// without an entry the server may still not need a password.
+func (conn Connection) withCredentials() (Connection, error) {
+	user, pass, err := credentials(conn.Name)
+	if err != nil {
+		return conn, err
+	}
+
+	conn.User = user
+	conn.Pass = pass
+
+	if conn.PgpassLinked {
+		if pass, err := pgpassPassword(conn); err == nil {
+			conn.Pass = pass
+		}
+	}
+
+	return conn, nil
+}
+
+// LoadConnection returns the saved connection with its credentials.
+func LoadConnection(name string) (Connection, error) {
+	conns, err := ListConnections()
+	if err != nil {
+		return Connection{}, err
+	}
+
+	for _, conn := range conns {
+		if conn.Name == name {
+			return conn.withCredentials()
+		}
+	}
+
+	return Connection{}, fmt.Errorf("no saved connection named %q", name)
+}
+
+// DeleteConnection removes a saved connection and its credentials.
+func DeleteConnection(name string) error {
+	if err := deleteLocalDbConn(name); err != nil {
+		return err
+	}
+
+	if err := keyring.Delete(SERVICE, name); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
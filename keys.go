@@ -0,0 +1,804 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// welcomeKeyMap holds the keys of the welcome menu.
+type welcomeKeyMap struct {
+	Select key.Binding
+	Quit   key.Binding
+	Help   key.Binding
+}
+
+func newWelcomeKeyMap() welcomeKeyMap {
+	return welcomeKeyMap{
+		Select: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "select")),
+		Quit:   key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+		Help:   key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
+	}
+}
+
+func (k *welcomeKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"select": &k.Select,
+		"quit":   &k.Quit,
+		"help":   &k.Help,
+	}
+}
+
+// connectionKeyMap holds the keys of the new connection form. The form is
+// all text fields so only keys that do not type are bound.
+type connectionKeyMap struct {
+	Cancel       key.Binding
+	NextField    key.Binding
+	PrevField    key.Binding
+	SwitchButton key.Binding
+	Submit       key.Binding
+	Driver       key.Binding
+	ReadOnly     key.Binding
+	CursorMode   key.Binding
+	Paste        key.Binding
+}
+
+func newConnectionKeyMap() connectionKeyMap {
+	return connectionKeyMap{
+		Cancel:       key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "cancel")),
+		NextField:    key.NewBinding(key.WithKeys("tab", "down"), key.WithHelp("tab", "next field")),
+		PrevField:    key.NewBinding(key.WithKeys("shift+tab", "up"), key.WithHelp("shift+tab", "previous field")),
+		SwitchButton: key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "switch button")),
+		Submit:       key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "press button")),
+		Driver:       key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("ctrl+t", "type")),
+		ReadOnly:     key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "read-only")),
+		CursorMode:   key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "cursor mode")),
+		Paste:        key.NewBinding(key.WithKeys("ctrl+l"), key.WithHelp("ctrl+l", "paste a connection URL")),
+	}
+}
+
+func (k *connectionKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"cancel":        &k.Cancel,
+		"next_field":    &k.NextField,
+		"prev_field":    &k.PrevField,
+		"switch_button": &k.SwitchButton,
+		"submit":        &k.Submit,
+		"driver":        &k.Driver,
+		"read_only":     &k.ReadOnly,
+		"cursor_mode":   &k.CursorMode,
+		"paste":         &k.Paste,
+	}
+}
+
+// pasteKeyMap holds the keys of the paste mode of the connection form.
+type pasteKeyMap struct {
+	Format key.Binding
+	Fill   key.Binding
+	Back   key.Binding
+}
+
+func newPasteKeyMap() pasteKeyMap {
+	return pasteKeyMap{
+		Format: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "url/key=value")),
+		Fill:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "fill fields")),
+		Back:   key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+	}
+}
+
+func (k *pasteKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"format": &k.Format,
+		"fill":   &k.Fill,
+		"back":   &k.Back,
+	}
+}
+
+// connectionsKeyMap holds the keys of the list of saved connections.
+type connectionsKeyMap struct {
+	Quit        key.Binding
+	Sort        key.Binding
+	Favorite    key.Binding
+	Select      key.Binding
+	ToggleGroup key.Binding
+}
+
+func newConnectionsKeyMap() connectionsKeyMap {
+	return connectionsKeyMap{
+		Quit:        key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "back")),
+		Sort:        key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
+		Favorite:    key.NewBinding(key.WithKeys("*"), key.WithHelp("*", "favorite")),
+		Select:      key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "open/fold")),
+		ToggleGroup: key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "fold")),
+	}
+}
+
+func (k *connectionsKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":         &k.Quit,
+		"sort":         &k.Sort,
+		"favorite":     &k.Favorite,
+		"select":       &k.Select,
+		"toggle_group": &k.ToggleGroup,
+	}
+}
+
+// databaseKeyMap holds the keys of the database view while browsing tables
+// and results.
+type databaseKeyMap struct {
+	Quit              key.Binding
+	SwitchPane        key.Binding
+	NextResult        key.Binding
+	PrevResult        key.Binding
+	Editor            key.Binding
+	Saved             key.Binding
+	History           key.Binding
+	Import            key.Binding
+	DDL               key.Binding
+	Refresh           key.Binding
+	Begin             key.Binding
+	Commit            key.Binding
+	Rollback          key.Binding
+	Savepoint         key.Binding
+	RollbackSavepoint key.Binding
+	Help              key.Binding
+}
+
+func newDatabaseKeyMap() databaseKeyMap {
+	return databaseKeyMap{
+		Quit:              key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "back")),
+		SwitchPane:        key.NewBinding(key.WithKeys("left", "right"), key.WithHelp("←/→", "switch pane")),
+		NextResult:        key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next result")),
+		PrevResult:        key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "previous result")),
+		Editor:            key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "editor")),
+		Saved:             key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "saved queries")),
+		History:           key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history")),
+		Import:            key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "import")),
+		DDL:               key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "ddl")),
+		Refresh:           key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "refresh")),
+		Begin:             key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "begin transaction")),
+		Commit:            key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "commit")),
+		Rollback:          key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rollback")),
+		Savepoint:         key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "savepoint")),
+		RollbackSavepoint: key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "rollback to savepoint")),
+		Help:              key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
+	}
+}
+
+// actions names the bindings for the config file.
+func (k *databaseKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":               &k.Quit,
+		"switch_pane":        &k.SwitchPane,
+		"next_result":        &k.NextResult,
+		"prev_result":        &k.PrevResult,
+		"editor":             &k.Editor,
+		"saved":              &k.Saved,
+		"history":            &k.History,
+		"import":             &k.Import,
+		"ddl":                &k.DDL,
+		"refresh":            &k.Refresh,
+		"begin":              &k.Begin,
+		"commit":             &k.Commit,
+		"rollback":           &k.Rollback,
+		"savepoint":          &k.Savepoint,
+		"rollback_savepoint": &k.RollbackSavepoint,
+		"help":               &k.Help,
+	}
+}
+
+// ShortHelp and FullHelp make the map a help.KeyMap for the overlay.
+func (k databaseKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Editor, k.Help, k.Quit}
+}
+
+func (k databaseKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.SwitchPane, k.NextResult, k.PrevResult, k.Refresh, k.Help, k.Quit},
+		{k.Editor, k.Saved, k.History, k.Import, k.DDL},
+		{k.Begin, k.Commit, k.Rollback, k.Savepoint, k.RollbackSavepoint},
+		{editorKeys.Run, editorKeys.Explain, editorKeys.SaveQuery, editorKeys.OnError, editorKeys.Help},
+	}
+}
+
+// editorKeyMap holds the keys of the query editor. Keys that type are left
+// to the editor, so help is on f1 rather than "?".
+type editorKeyMap struct {
+	Run       key.Binding
+	Explain   key.Binding
+	SaveQuery key.Binding
+	OnError   key.Binding
+	Complete  key.Binding
+	Results   key.Binding
+	Help      key.Binding
+}
+
+func newEditorKeyMap() editorKeyMap {
+	return editorKeyMap{
+		Run:       key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "run")),
+		Explain:   key.NewBinding(key.WithKeys("ctrl+x"), key.WithHelp("ctrl+x", "explain")),
+		SaveQuery: key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save")),
+		OnError:   key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("ctrl+o", "on error")),
+		Complete:  key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete")),
+		Results:   key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "results")),
+		Help:      key.NewBinding(key.WithKeys("f1"), key.WithHelp("f1", "help")),
+	}
+}
+
+func (k *editorKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"run":        &k.Run,
+		"explain":    &k.Explain,
+		"save_query": &k.SaveQuery,
+		"on_error":   &k.OnError,
+		"complete":   &k.Complete,
+		"results":    &k.Results,
+		"help":       &k.Help,
+	}
+}
+
+func (k editorKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Run, k.Help, k.Results}
+}
+
+func (k editorKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Run, k.Explain, k.SaveQuery, k.OnError, k.Help, k.Results},
+		{k.Complete, completionKeys.Accept, completionKeys.Prev, completionKeys.Next, completionKeys.Close},
+	}
+}
+
+// completionKeyMap holds the keys of the completion menu of the editor.
+type completionKeyMap struct {
+	Accept key.Binding
+	Prev   key.Binding
+	Next   key.Binding
+	Close  key.Binding
+}
+
+func newCompletionKeyMap() completionKeyMap {
+	return completionKeyMap{
+		Accept: key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "accept completion")),
+		Prev:   key.NewBinding(key.WithKeys("up", "ctrl+p"), key.WithHelp("↑/ctrl+p", "previous completion")),
+		Next:   key.NewBinding(key.WithKeys("down", "ctrl+n"), key.WithHelp("↓/ctrl+n", "next completion")),
+		Close:  key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close completions")),
+	}
+}
+
+func (k *completionKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"accept": &k.Accept,
+		"prev":   &k.Prev,
+		"next":   &k.Next,
+		"close":  &k.Close,
+	}
+}
+
+// promptKeyMap holds the keys shared by the views that ask for text, such as
+// a file path, a search or the values of parameters.
+type promptKeyMap struct {
+	Submit    key.Binding
+	Cancel    key.Binding
+	NextField key.Binding
+	PrevField key.Binding
+}
+
+func newPromptKeyMap() promptKeyMap {
+	return promptKeyMap{
+		Submit:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "confirm")),
+		Cancel:    key.NewBinding(key.WithKeys("esc", "ctrl+c"), key.WithHelp("esc", "cancel")),
+		NextField: key.NewBinding(key.WithKeys("tab", "down"), key.WithHelp("tab", "next field")),
+		PrevField: key.NewBinding(key.WithKeys("shift+tab", "up"), key.WithHelp("shift+tab", "previous field")),
+	}
+}
+
+func (k *promptKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"submit":     &k.Submit,
+		"cancel":     &k.Cancel,
+		"next_field": &k.NextField,
+		"prev_field": &k.PrevField,
+	}
+}
+
+// pagerKeyMap holds the keys of text shown in a viewport, such as the DDL of
+// a table. Scrolling uses the keys of the viewport.
+type pagerKeyMap struct {
+	Close key.Binding
+}
+
+func newPagerKeyMap() pagerKeyMap {
+	return pagerKeyMap{
+		Close: key.NewBinding(key.WithKeys("q", "esc", "enter", "ctrl+c"), key.WithHelp("esc", "back")),
+	}
+}
+
+func (k *pagerKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"close": &k.Close,
+	}
+}
+
+// gridKeyMap holds the keys of the grid of a file opened with view.
+type gridKeyMap struct {
+	Quit    key.Binding
+	Back    key.Binding
+	Left    key.Binding
+	Right   key.Binding
+	Sort    key.Binding
+	Search  key.Binding
+	Inspect key.Binding
+	Export  key.Binding
+}
+
+func newGridKeyMap() gridKeyMap {
+	return gridKeyMap{
+		Quit:    key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
+		Back:    key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear search")),
+		Left:    key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←", "previous column")),
+		Right:   key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→", "next column")),
+		Sort:    key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "sort")),
+		Search:  key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+		Inspect: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "inspect row")),
+		Export:  key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "export")),
+	}
+}
+
+func (k *gridKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":    &k.Quit,
+		"back":    &k.Back,
+		"left":    &k.Left,
+		"right":   &k.Right,
+		"sort":    &k.Sort,
+		"search":  &k.Search,
+		"inspect": &k.Inspect,
+		"export":  &k.Export,
+	}
+}
+
+// importKeyMap holds the keys of the steps of importing a file into a table.
+type importKeyMap struct {
+	Quit       key.Binding
+	Back       key.Binding
+	Next       key.Binding
+	Up         key.Binding
+	Down       key.Binding
+	PrevSource key.Binding
+	NextSource key.Binding
+}
+
+func newImportKeyMap() importKeyMap {
+	return importKeyMap{
+		Quit:       key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+		Back:       key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		Next:       key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "next")),
+		Up:         key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑", "previous column")),
+		Down:       key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓", "next column")),
+		PrevSource: key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "previous source")),
+		NextSource: key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "next source")),
+	}
+}
+
+func (k *importKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":        &k.Quit,
+		"back":        &k.Back,
+		"next":        &k.Next,
+		"up":          &k.Up,
+		"down":        &k.Down,
+		"prev_source": &k.PrevSource,
+		"next_source": &k.NextSource,
+	}
+}
+
+// historyKeyMap holds the keys of the query history.
+type historyKeyMap struct {
+	Quit key.Binding
+	Back key.Binding
+	Load key.Binding
+	Run  key.Binding
+}
+
+func newHistoryKeyMap() historyKeyMap {
+	return historyKeyMap{
+		Quit: key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "back")),
+		Back: key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		Load: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "load into editor")),
+		Run:  key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "run")),
+	}
+}
+
+func (k *historyKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit": &k.Quit,
+		"back": &k.Back,
+		"load": &k.Load,
+		"run":  &k.Run,
+	}
+}
+
+// savedQueriesKeyMap holds the keys of the saved queries and of saving one.
+type savedQueriesKeyMap struct {
+	Quit   key.Binding
+	Back   key.Binding
+	Run    key.Binding
+	Open   key.Binding
+	Delete key.Binding
+	Export key.Binding
+	Import key.Binding
+	Scope  key.Binding
+}
+
+func newSavedQueriesKeyMap() savedQueriesKeyMap {
+	return savedQueriesKeyMap{
+		Quit:   key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "back")),
+		Back:   key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
+		Run:    key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run")),
+		Open:   key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "open in editor")),
+		Delete: key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete")),
+		Export: key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "export")),
+		Import: key.NewBinding(key.WithKeys("I"), key.WithHelp("I", "import")),
+		Scope:  key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "toggle global/connection")),
+	}
+}
+
+func (k *savedQueriesKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":   &k.Quit,
+		"back":   &k.Back,
+		"run":    &k.Run,
+		"open":   &k.Open,
+		"delete": &k.Delete,
+		"export": &k.Export,
+		"import": &k.Import,
+		"scope":  &k.Scope,
+	}
+}
+
+// explainKeyMap holds the keys of the plan tree of EXPLAIN.
+type explainKeyMap struct {
+	Back     key.Binding
+	Analyze  key.Binding
+	Buffers  key.Binding
+	Up       key.Binding
+	Down     key.Binding
+	Toggle   key.Binding
+	Collapse key.Binding
+	Expand   key.Binding
+}
+
+func newExplainKeyMap() explainKeyMap {
+	return explainKeyMap{
+		Back:     key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("esc", "back")),
+		Analyze:  key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "analyze")),
+		Buffers:  key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "buffers")),
+		Up:       key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑", "previous node")),
+		Down:     key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓", "next node")),
+		Toggle:   key.NewBinding(key.WithKeys("enter", " "), key.WithHelp("enter", "collapse/expand")),
+		Collapse: key.NewBinding(key.WithKeys("left", "h"), key.WithHelp("←", "collapse")),
+		Expand:   key.NewBinding(key.WithKeys("right", "l"), key.WithHelp("→", "expand")),
+	}
+}
+
+func (k *explainKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"back":     &k.Back,
+		"analyze":  &k.Analyze,
+		"buffers":  &k.Buffers,
+		"up":       &k.Up,
+		"down":     &k.Down,
+		"toggle":   &k.Toggle,
+		"collapse": &k.Collapse,
+		"expand":   &k.Expand,
+	}
+}
+
+// shareKeyMap holds the keys of choosing connections to export or import.
+// The file step uses the prompt keys.
+type shareKeyMap struct {
+	Quit      key.Binding
+	Back      key.Binding
+	Toggle    key.Binding
+	SelectAll key.Binding
+	Passwords key.Binding
+	Resolve   key.Binding
+	Next      key.Binding
+}
+
+func newShareKeyMap() shareKeyMap {
+	return shareKeyMap{
+		Quit:      key.NewBinding(key.WithKeys("ctrl+c"), key.WithHelp("ctrl+c", "quit")),
+		Back:      key.NewBinding(key.WithKeys("q", "esc"), key.WithHelp("esc", "back")),
+		Toggle:    key.NewBinding(key.WithKeys(" ", "x"), key.WithHelp("space", "select")),
+		SelectAll: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "select all")),
+		Passwords: key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "passwords")),
+		Resolve:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "resolve conflict")),
+		Next:      key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "choose file")),
+	}
+}
+
+func (k *shareKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":       &k.Quit,
+		"back":       &k.Back,
+		"toggle":     &k.Toggle,
+		"select_all": &k.SelectAll,
+		"passwords":  &k.Passwords,
+		"resolve":    &k.Resolve,
+		"next":       &k.Next,
+	}
+}
+
+// discoverKeyMap holds the keys of choosing discovered connections to save.
+type discoverKeyMap struct {
+	Back      key.Binding
+	Toggle    key.Binding
+	SelectAll key.Binding
+	Link      key.Binding
+	Save      key.Binding
+}
+
+func newDiscoverKeyMap() discoverKeyMap {
+	return discoverKeyMap{
+		Back:      key.NewBinding(key.WithKeys("q", "esc", "ctrl+c"), key.WithHelp("esc", "back")),
+		Toggle:    key.NewBinding(key.WithKeys(" ", "x"), key.WithHelp("space", "select")),
+		SelectAll: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "select all")),
+		Link:      key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "link password to pgpass")),
+		Save:      key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save")),
+	}
+}
+
+func (k *discoverKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"back":       &k.Back,
+		"toggle":     &k.Toggle,
+		"select_all": &k.SelectAll,
+		"link":       &k.Link,
+		"save":       &k.Save,
+	}
+}
+
+// paramsKeyMap holds the keys of the parameter form besides the prompt keys.
+type paramsKeyMap struct {
+	Null key.Binding
+}
+
+func newParamsKeyMap() paramsKeyMap {
+	return paramsKeyMap{
+		Null: key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("ctrl+n", "toggle NULL")),
+	}
+}
+
+func (k *paramsKeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"null": &k.Null,
+	}
+}
+
+var (
+	welcomeKeys      = newWelcomeKeyMap()
+	connectionKeys   = newConnectionKeyMap()
+	pasteKeys        = newPasteKeyMap()
+	connectionsKeys  = newConnectionsKeyMap()
+	databaseKeys     = newDatabaseKeyMap()
+	editorKeys       = newEditorKeyMap()
+	completionKeys   = newCompletionKeyMap()
+	promptKeys       = newPromptKeyMap()
+	pagerKeys        = newPagerKeyMap()
+	gridKeys         = newGridKeyMap()
+	importKeys       = newImportKeyMap()
+	historyKeys      = newHistoryKeyMap()
+	savedQueriesKeys = newSavedQueriesKeyMap()
+	explainKeys      = newExplainKeyMap()
+	shareKeys        = newShareKeyMap()
+	discoverKeys     = newDiscoverKeyMap()
+	paramsKeys       = newParamsKeyMap()
+)
+
+// keyMaps are the bindings of each view by the name used in the config file.
+// The actions of a view are active at the same time, so no two may share a
+// key.
+var keyMaps = map[string]map[string]*key.Binding{
+	"welcome":       welcomeKeys.actions(),
+	"connection":    connectionKeys.actions(),
+	"paste":         pasteKeys.actions(),
+	"connections":   connectionsKeys.actions(),
+	"database":      databaseKeys.actions(),
+	"editor":        editorKeys.actions(),
+	"completion":    completionKeys.actions(),
+	"prompt":        promptKeys.actions(),
+	"pager":         pagerKeys.actions(),
+	"grid":          gridKeys.actions(),
+	"import":        importKeys.actions(),
+	"history":       historyKeys.actions(),
+	"saved_queries": savedQueriesKeys.actions(),
+	"explain":       explainKeys.actions(),
+	"share":         shareKeys.actions(),
+	"discover":      discoverKeys.actions(),
+	"params":        paramsKeys.actions(),
+}
+
+// keyPresets are sets of bindings chosen with key_preset, applied before
+// the keys of the config file.
+var keyPresets = map[string]map[string]map[string][]string{
+	"default": {},
+	"vim": {
+		"welcome": {
+			"select": {"enter", "l"},
+		},
+		"connection": {
+			"next_field": {"tab", "down", "ctrl+j"},
+			"prev_field": {"shift+tab", "up", "ctrl+k"},
+		},
+		"database": {
+			"switch_pane": {"h", "l", "left", "right"},
+			"next_result": {"tab", "]"},
+			"prev_result": {"shift+tab", "["},
+			"editor":      {"i", "e"},
+			"import":      {"I"},
+		},
+	},
+}
+
+// keyNames are the names bubbletea gives keys other than characters, from
+// the special keys below zero to the control characters and backspace.
+var keyNames = func() map[string]bool {
+	names := map[string]bool{}
+	for k := tea.KeyF20; k <= tea.KeyBackspace; k++ {
+		if name := k.String(); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}()
+
+// validKey reports whether a key is written the way bubbletea names it, a
+// single character or a name such as "ctrl+r", with an optional "alt+".
+func validKey(name string) bool {
+	name = strings.TrimPrefix(name, "alt+")
+	return utf8.RuneCountInString(name) == 1 || keyNames[name]
+}
+
+// validateKeys drops the bindings of unknown views and actions and those
+// with invalid keys, then those that give two actions of a view the same key
+// once the preset is applied, returning what was wrong with them. Views are
+// checked on their own, so keys shared with another view are allowed.
+func validateKeys(preset string, keys map[string]map[string][]string) []string {
+	var problems []string
+
+	for view, actions := range keys {
+		bindings, ok := keyMaps[view]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown view keys.%s", view))
+			delete(keys, view)
+			continue
+		}
+
+		for action, names := range actions {
+			if _, ok := bindings[action]; !ok {
+				problems = append(problems, fmt.Sprintf("unknown action keys.%s.%s", view, action))
+				delete(actions, action)
+				continue
+			}
+
+			if len(names) == 0 {
+				problems = append(problems, fmt.Sprintf("keys.%s.%s needs at least one key", view, action))
+				delete(actions, action)
+				continue
+			}
+
+			for _, name := range names {
+				if !validKey(name) {
+					problems = append(problems, fmt.Sprintf("keys.%s.%s: unknown key %q", view, action, name))
+					delete(actions, action)
+					break
+				}
+			}
+		}
+
+		// Dropping a binding brings back the one it replaced, which may clash
+		// in turn
+		for {
+			name, first, second, ok := duplicateKey(view, keyPresets[preset][view], actions)
+			if !ok {
+				break
+			}
+			problems = append(problems, fmt.Sprintf("keys.%s: %q is bound to both %s and %s", view, name, first, second))
+
+			if _, ok := actions[second]; ok {
+				delete(actions, second)
+			} else if _, ok := actions[first]; ok {
+				delete(actions, first)
+			} else {
+				break
+			}
+		}
+	}
+
+	return problems
+}
+
+// duplicateKey finds a key bound to two actions of a view, with the keys of
+// the actions set by the preset and then by overrides.
+func duplicateKey(view string, preset map[string][]string, overrides map[string][]string) (string, string, string, bool) {
+	keys := map[string][]string{}
+	for action, binding := range keyMaps[view] {
+		keys[action] = binding.Keys()
+	}
+	for action, names := range preset {
+		keys[action] = names
+	}
+	for action, names := range overrides {
+		keys[action] = names
+	}
+
+	actions := make([]string, 0, len(keys))
+	for action := range keys {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	bound := map[string]string{}
+	for _, action := range actions {
+		for _, name := range keys[action] {
+			if other, ok := bound[name]; ok && other != action {
+				return name, other, action, true
+			}
+			bound[name] = action
+		}
+	}
+
+	return "", "", "", false
+}
+
+// applyKeys rebinds the actions of the preset and then those set in the
+// config file, showing their keys in the help.
+func applyKeys(preset string, keys map[string]map[string][]string) {
+	bindKeys(keyPresets[preset])
+	bindKeys(keys)
+}
+
+func bindKeys(keys map[string]map[string][]string) {
+	for view, actions := range keys {
+		for action, names := range actions {
+			binding := keyMaps[view][action]
+			binding.SetKeys(names...)
+			binding.SetHelp(strings.Join(names, "/"), binding.Help().Desc)
+		}
+	}
+}
+
+// helpOverlay renders every binding of a view in columns, shown in place of
+// the view until it is closed.
+func helpOverlay(keys help.KeyMap, closeKey key.Binding) string {
+	h := help.New()
+	h.ShowAll = true
+	h.Styles.FullKey = focusedItemStyle
+	h.Styles.FullDesc = noStyle
+	h.Styles.FullSeparator = blurredStyle
+
+	return overlayStyle.Render(h.View(keys)) + helpStyle.Render("\n"+keyHelp(closeKey))
+}
+
+// withHelp returns a copy of a binding described as desc, for views that
+// name the same action differently.
+func withHelp(binding key.Binding, desc string) key.Binding {
+	binding.SetHelp(binding.Help().Key, desc)
+	return binding
+}
+
+// keyHelp renders the help line of the bindings.
+func keyHelp(bindings ...key.Binding) string {
+	parts := make([]string, 0, len(bindings))
+	for _, binding := range bindings {
+		parts = append(parts, fmt.Sprintf("%s: %s", binding.Help().Key, binding.Help().Desc))
+	}
+
+	return strings.Join(parts, " • ")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyPresetsHaveNoDuplicates(t *testing.T) {
+	for preset := range keyPresets {
+		for view := range keyMaps {
+			if name, first, second, ok := duplicateKey(view, keyPresets[preset][view], nil); ok {
+				t.Errorf("preset %s: keys.%s: %q is bound to both %s and %s", preset, view, name, first, second)
+			}
+		}
+	}
+}
+
+func TestValidateKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		keys     map[string]map[string][]string
+		want     map[string]map[string][]string
+		problems []string
+	}{
+		{
+			name:   "swapped keys",
+			preset: "default",
+			keys:   map[string]map[string][]string{"editor": {"run": {"ctrl+x"}, "explain": {"ctrl+r"}}},
+			want:   map[string]map[string][]string{"editor": {"run": {"ctrl+x"}, "explain": {"ctrl+r"}}},
+		},
+		{
+			name:     "key of another action",
+			preset:   "default",
+			keys:     map[string]map[string][]string{"editor": {"run": {"ctrl+x", "f5"}}},
+			want:     map[string]map[string][]string{"editor": {}},
+			problems: []string{`keys.editor: "ctrl+x" is bound to both explain and run`},
+		},
+		{
+			name:   "dropping one brings back a clashing default",
+			preset: "default",
+			keys:   map[string]map[string][]string{"editor": {"run": {"ctrl+r"}, "explain": {"ctrl+r"}}},
+			want:   map[string]map[string][]string{"editor": {}},
+			problems: []string{
+				`keys.editor: "ctrl+r" is bound to both explain and run`,
+				`keys.editor: "ctrl+r" is bound to both explain and run`,
+			},
+		},
+		{
+			name:     "key of the preset",
+			preset:   "vim",
+			keys:     map[string]map[string][]string{"database": {"editor": {"l"}}},
+			want:     map[string]map[string][]string{"database": {}},
+			problems: []string{`keys.database: "l" is bound to both editor and switch_pane`},
+		},
+		{
+			name:   "same key in different views",
+			preset: "default",
+			keys:   map[string]map[string][]string{"grid": {"sort": {"o"}}, "explain": {"analyze": {"o"}}},
+			want:   map[string]map[string][]string{"grid": {"sort": {"o"}}, "explain": {"analyze": {"o"}}},
+		},
+		{
+			name:   "unknown names and keys",
+			preset: "default",
+			keys: map[string]map[string][]string{
+				"nowhere": {"run": {"r"}},
+				"grid":    {"fly": {"f"}, "sort": {"sort"}, "search": {}, "export": {" "}},
+			},
+			want: map[string]map[string][]string{"grid": {"export": {" "}}},
+			problems: []string{
+				"keys.grid.search needs at least one key",
+				`keys.grid.sort: unknown key "sort"`,
+				"unknown action keys.grid.fly",
+				"unknown view keys.nowhere",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		problems := validateKeys(test.preset, test.keys)
+		sort.Strings(problems)
+		sort.Strings(test.problems)
+
+		if !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: problems\n got %q\nwant %q", test.name, problems, test.problems)
+		}
+		if !reflect.DeepEqual(test.keys, test.want) {
+			t.Errorf("%s: keys\n got %v\nwant %v", test.name, test.keys, test.want)
+		}
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	for _, name := range []string{"a", "?", " ", "ctrl+r", "ctrl+c", "backspace", "esc", "f1", "shift+tab", "alt+enter", "alt+x"} {
+		if !validKey(name) {
+			t.Errorf("validKey(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"", "ab", "space", "ctrl+", "hyper+a"} {
+		if validKey(name) {
+			t.Errorf("validKey(%q) = true", name)
+		}
+	}
+}
+
+func TestEditorHelp(t *testing.T) {
+	db := OpenDatabase{viewMode: QUERY}
+
+	db, _ = db.Update(tea.KeyMsg{Type: tea.KeyF1})
+	if !db.showHelp {
+		t.Fatal("f1 in the editor did not open the help")
+	}
+
+	// The overlay takes the keys, run does nothing until it is closed
+	db, cmd := db.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if !db.showHelp || cmd != nil || db.viewMode != QUERY {
+		t.Errorf("ctrl+r with the help open: help = %v, cmd = %v, mode = %v", db.showHelp, cmd != nil, db.viewMode)
+	}
+
+	db, _ = db.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if db.showHelp || db.viewMode != QUERY {
+		t.Errorf("esc closed help = %v, mode = %v, want the editor back", !db.showHelp, db.viewMode)
+	}
+}
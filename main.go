@@ -3,9 +3,11 @@ package main
 import (
 	"fmt"
 	"io"
-	"log"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -19,12 +21,11 @@ const (
 	EDIT_CONNECTION CurrentView = "EDIT_CONNECTION"
 	JOIN_EXISTING   CurrentView = "JOIN_EXISTING"
 	DATABASE_VIEW   CurrentView = "DATABASE_VIEW"
+	DISCOVER        CurrentView = "DISCOVER"
+	SHARE           CurrentView = "SHARE"
 )
 
-const (
-	defaultWidth = 20
-	listHeight   = 14
-)
+const defaultWidth = 20
 
 // Primary ansi colours
 const (
@@ -43,16 +44,18 @@ var (
 	itemStyle       = lipgloss.NewStyle().PaddingLeft(4)
 	paginationStyle = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
 	quitTextStyle   = lipgloss.NewStyle().Margin(1, 0, 2, 0)
-	helpStyle       = blurredStyle.Copy().PaddingLeft(2)
-	cursorStyle     = focusedItemStyle.Copy()
 	noStyle         = lipgloss.NewStyle()
 
-	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color(MAGENTA))
-	focusedItemStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color(RED))
-	focusedStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color(WHITE))
-	blurredStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color(GREY))
-	successStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color(GREEN))
-	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color(RED))
+	// Set from the theme
+	helpStyle         lipgloss.Style
+	cursorStyle       lipgloss.Style
+	selectedItemStyle lipgloss.Style
+	focusedItemStyle  lipgloss.Style
+	focusedStyle      lipgloss.Style
+	blurredStyle      lipgloss.Style
+	successStyle      lipgloss.Style
+	errorStyle        lipgloss.Style
+	overlayStyle      lipgloss.Style
 
 	width  int = 100
 	height int = 100
@@ -92,6 +95,14 @@ type model struct {
 	currentConnection   Connection
 	openDatabase        OpenDatabase
 	existingConnections ExistingConnectionsModel
+	discoverConnections DiscoverConnectionsModel
+	shareConnections    ShareConnectionsModel
+
+	// Opened once the terminal size is known
+	pendingConnection *Connection
+
+	// Shown until the first view is left, such as problems with the config file
+	startupErr error
 }
 
 func (m model) updateEvents(msg tea.Msg) (model, tea.Cmd) {
@@ -103,11 +114,12 @@ func (m model) updateEvents(msg tea.Msg) (model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		switch keypress := msg.String(); keypress {
-		case "q", "ctrl+c":
+		switch {
+		case key.Matches(msg, welcomeKeys.Quit):
 			return m, tea.Quit
 
-		case "enter":
+		case key.Matches(msg, welcomeKeys.Select):
+			m.startupErr = nil
 			i, ok := m.list.SelectedItem().(item)
 			if ok {
 				switch string(i) {
@@ -120,6 +132,17 @@ func (m model) updateEvents(msg tea.Msg) (model, tea.Cmd) {
 				case "Join Existing":
 					m.currentView = JOIN_EXISTING
 					m.existingConnections = NewExistingConnectionsModel()
+				case "Import from pgpass":
+					m.currentView = DISCOVER
+					m.discoverConnections = NewDiscoverConnectionsModel()
+				case "Import from File":
+					var cmd tea.Cmd
+					m.currentView = SHARE
+					m.shareConnections, cmd = NewImportConnectionsModel()
+					return m, cmd
+				case "Export to File":
+					m.currentView = SHARE
+					m.shareConnections = NewExportConnectionsModel()
 				}
 			}
 			return m, nil
@@ -135,15 +158,38 @@ func (m model) Init() tea.Cmd {
 	return nil
 }
 
+// withConnection starts the model in the database view of conn.
+func (m model) withConnection(conn Connection) model {
+	m.currentView = DATABASE_VIEW
+	m.currentConnection = conn
+	m.pendingConnection = &conn
+	return m
+}
+
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	if msg, ok := msg.(tea.WindowSizeMsg); ok && m.pendingConnection != nil {
+		m.list.SetWidth(msg.Width)
+		width = msg.Width
+		height = msg.Height
+
+		m.openDatabase = NewOpenDatabase(*m.pendingConnection)
+		m.pendingConnection = nil
+		if m.openDatabase.err == nil {
+			m.openDatabase.err = m.startupErr
+		}
+		m.startupErr = nil
+		return m, nil
+	}
+
 	switch m.currentView {
 	case NEW_CONNECTION:
 		m.newConnectionModel, cmd = m.newConnectionModel.Update(msg)
 		if m.newConnectionModel.connection.status == CONNECTED {
 			m.currentView = DATABASE_VIEW
 			m.currentConnection = m.newConnectionModel.connection
+			m.currentConnection.LastUsed = time.Now()
 			m.openDatabase = NewOpenDatabase(m.currentConnection)
 
 			SaveConnectionInKeyring(m.currentConnection)
@@ -157,6 +203,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.openDatabase, cmd = m.openDatabase.Update(msg)
 		if m.openDatabase.viewMode == QUIT {
 			m.currentView = DEFAULT
+			m.openDatabase.Close()
 			m.openDatabase = OpenDatabase{}
 		}
 
@@ -166,12 +213,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.currentView = DATABASE_VIEW
 			m.currentConnection = *m.existingConnections.selectedConnection
 			m.openDatabase = NewOpenDatabase(m.currentConnection)
+
+			if err := MarkConnectionUsed(&m.currentConnection); err != nil && m.openDatabase.err == nil {
+				m.openDatabase.err = fmt.Errorf("could not record last use: %w", err)
+			}
 		}
 
 		if m.existingConnections.back {
 			m.currentView = DEFAULT
 		}
 
+	case DISCOVER:
+		m.discoverConnections, cmd = m.discoverConnections.Update(msg)
+		if m.discoverConnections.back {
+			m.currentView = DEFAULT
+		}
+
+	case SHARE:
+		m.shareConnections, cmd = m.shareConnections.Update(msg)
+		if m.shareConnections.back {
+			m.currentView = DEFAULT
+		}
+
 	case DEFAULT, EDIT_CONNECTION:
 		m, cmd = m.updateEvents(msg)
 	}
@@ -187,21 +250,35 @@ func (m model) View() string {
 		return quitTextStyle.Render("Edit Connection")
 	case JOIN_EXISTING:
 		return quitTextStyle.Render(m.existingConnections.View())
+	case DISCOVER:
+		return quitTextStyle.Render(m.discoverConnections.View())
+	case SHARE:
+		return quitTextStyle.Render(m.shareConnections.View())
 	case DATABASE_VIEW:
+		if m.pendingConnection != nil {
+			return quitTextStyle.Render("Connecting to " + m.pendingConnection.Name + "...")
+		}
 		return quitTextStyle.Render(m.openDatabase.View())
 	default:
-		return "\n" + m.list.View()
+		s := "\n" + m.list.View()
+		if m.startupErr != nil {
+			s += "\n" + errorStyle.Render(m.startupErr.Error())
+		}
+		return s
 	}
 }
 
-func main() {
+func newModel() model {
 	items := []list.Item{
 		item("New Connection"),
 		item("Edit Connection"),
 		item("Join Existing"),
+		item("Import from pgpass"),
+		item("Import from File"),
+		item("Export to File"),
 	}
 
-	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
+	l := list.New(items, itemDelegate{}, defaultWidth, cfg.PageSize)
 	l.Title = "Welcome to TermTable"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false)
@@ -209,9 +286,17 @@ func main() {
 	l.Styles.PaginationStyle = paginationStyle
 	l.Styles.HelpStyle = helpStyle
 
-	m := model{list: l, currentView: DEFAULT}
+	// The list shows the full help with the help key
+	l.KeyMap.Quit = welcomeKeys.Quit
+	l.KeyMap.ForceQuit.SetEnabled(false)
+	l.KeyMap.ShowFullHelp = welcomeKeys.Help
+	l.KeyMap.CloseFullHelp = welcomeKeys.Help
+	l.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{welcomeKeys.Select} }
+	l.AdditionalFullHelpKeys = l.AdditionalShortHelpKeys
 
-	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
-		log.Fatal("Error running program:", err)
-	}
+	return model{list: l, currentView: DEFAULT}
+}
+
+func main() {
+	os.Exit(runCLI(os.Args[1:]))
 }
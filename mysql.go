@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var mysqlTLSModes = []string{"false", "true", "skip-verify", "preferred"}
+
+type mysqlDriver struct{}
+
+// Connect opens a connection with the character set and TLS settings of the
+// connection, through the SSH tunnel when one is open.
+func (mysqlDriver) Connect(params Connection) (DriverConn, error) {
+	if err := params.checkTunnel(); err != nil {
+		return nil, err
+	}
+	params = params.withDefaults()
+
+	config := mysql.NewConfig()
+	config.User = params.User
+	config.Passwd = params.Pass
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(params.Host, params.Port)
+	config.DBName = params.Database
+	config.Timeout = 10 * time.Second
+	// Dates are read as times so the grid can format them as configured, in
+	// UTC to keep the wall clock the server sent
+	config.ParseTime = true
+	config.Loc = time.UTC
+
+	if params.tunnelAddr != "" {
+		config.Addr = params.tunnelAddr
+	}
+
+	if params.Charset != "" {
+		config.Params = map[string]string{"charset": params.Charset}
+	}
+
+	tlsConfig, err := params.mysqlTLS()
+	if err != nil {
+		return nil, err
+	}
+	config.TLS = tlsConfig
+	config.AllowFallbackToPlaintext = params.TLS == "preferred"
+
+	connector, err := mysql.NewConnector(config)
+	if err != nil {
+		return nil, fmt.Errorf("mysql: %w", err)
+	}
+
+	conn, err := openSQLConn(sql.OpenDB(connector), mysqlDialect{})
+	if err != nil {
+		return nil, describeConnectError(err)
+	}
+
+	// Cancel kills statements by the id of the connection
+	var id int64
+	if err := conn.conn.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if params.ReadOnly {
+		if err := conn.Exec(context.Background(), "SET SESSION TRANSACTION READ ONLY"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &mysqlConn{sqlConn: conn, id: id}, nil
+}
+
+// mysqlTLS builds the TLS settings from the mode and certificate files. The
+// server certificate is checked against the host of the connection, not the
+// local end of the tunnel.
+func (params Connection) mysqlTLS() (*tls.Config, error) {
+	var config *tls.Config
+
+	switch params.TLS {
+	case "", "false":
+		return nil, nil
+	case "true":
+		config = &tls.Config{ServerName: params.Host}
+	default:
+		config = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	if params.SSLRootCert != "" {
+		pem, err := os.ReadFile(expandHome(params.SSLRootCert))
+		if err != nil {
+			return nil, fmt.Errorf("root CA: %w", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("root CA: no certificates found")
+		}
+	}
+
+	if params.SSLCert != "" {
+		cert, err := tls.LoadX509KeyPair(expandHome(params.SSLCert), expandHome(params.SSLKey))
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+type mysqlConn struct {
+	*sqlConn
+	// Connection id on the server
+	id int64
+}
+
+// Cancel kills the running statement from a second connection. Cancelling
+// its context would make the driver drop the connection, and with it any
+// open transaction.
+func (c *mysqlConn) Cancel() error {
+	if !c.running() {
+		return nil
+	}
+
+	_, err := c.db.ExecContext(context.Background(), fmt.Sprintf("KILL QUERY %d", c.id))
+	return err
+}
+
+func (c *mysqlConn) Tables() ([]string, error) {
+	return c.strings(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name`)
+}
+
+func (c *mysqlConn) Columns(table string) ([]Column, error) {
+	return c.columns(`SELECT column_name, column_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ?
+		ORDER BY ordinal_position`, table)
+}
+
+// TableDDL returns the statement the server gives for the table or view.
+func (c *mysqlConn) TableDDL(table string) (string, error) {
+	rows, err := c.conn.QueryContext(context.Background(),
+		fmt.Sprintf("SHOW CREATE TABLE %s", mysqlIdentifier(table)))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	// Tables answer with two columns and views with four, the statement is second
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("no table named %s", table)
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	scan := make([]any, len(columns))
+	for i := range values {
+		scan[i] = &values[i]
+	}
+	if err := rows.Scan(scan...); err != nil {
+		return "", err
+	}
+
+	return string(values[1]) + ";", nil
+}
+
+// Catalog reads every schema the user can see, with the current database
+// first so unqualified tables resolve to it.
+func (c *mysqlConn) Catalog() (Catalog, error) {
+	catalog := Catalog{
+		tables:  map[string][]string{},
+		columns: map[string][]Column{},
+	}
+
+	var err error
+	catalog.schemas, err = c.strings(
+		`SELECT schema_name FROM information_schema.schemata
+		WHERE schema_name NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+		ORDER BY schema_name = DATABASE() DESC, schema_name`)
+	if err != nil {
+		return Catalog{}, err
+	}
+
+	rows, err := c.conn.QueryContext(context.Background(),
+		`SELECT table_schema, table_name, column_name, column_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
+		ORDER BY table_schema, table_name, ordinal_position`)
+	if err != nil {
+		return Catalog{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, table string
+		var column Column
+		err = rows.Scan(&schema, &table, &column.Name, &column.DataType, &column.Nullable)
+		if err != nil {
+			return Catalog{}, err
+		}
+
+		key := catalogKey(schema, table)
+		if _, ok := catalog.columns[key]; !ok {
+			catalog.tables[schema] = append(catalog.tables[schema], table)
+		}
+		catalog.columns[key] = append(catalog.columns[key], column)
+	}
+	if err = rows.Err(); err != nil {
+		return Catalog{}, err
+	}
+
+	catalog.functions, err = c.strings(
+		`SELECT DISTINCT routine_name FROM information_schema.routines
+		WHERE routine_schema NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')`)
+	if err != nil {
+		return Catalog{}, err
+	}
+	catalog.functions = append(catalog.functions, builtinFunctions...)
+	sort.Strings(catalog.functions)
+
+	return catalog, nil
+}
+
+// mysqlIdentifier quotes a name with backticks.
+func mysqlIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) valueKind(column *sql.ColumnType) ValueKind {
+	declared := column.DatabaseTypeName()
+
+	switch {
+	case declared == "JSON":
+		return VALUE_JSON
+	case declared == "BIT",
+		strings.Contains(declared, "INT"),
+		strings.Contains(declared, "DECIMAL"),
+		strings.Contains(declared, "FLOAT"),
+		strings.Contains(declared, "DOUBLE"):
+		return VALUE_NUMBER
+	default:
+		return VALUE_TEXT
+	}
+}
+
+func (mysqlDialect) formatValue(column *sql.ColumnType, value any) string {
+	if t, ok := value.(time.Time); ok {
+		_, decimals, _ := column.DecimalSize()
+		return formatMySQLTime(column.DatabaseTypeName(), decimals, t)
+	}
+
+	return formatMySQLValue(column.DatabaseTypeName(), value)
+}
+
+// formatMySQLTime prints dates and timestamps the way the server sends them
+// as text, with as many fractional digits as the column declares. The driver
+// reads zero dates as the zero time.
+func formatMySQLTime(declared string, decimals int64, value time.Time) string {
+	if declared == "DATE" {
+		if value.IsZero() {
+			return "0000-00-00"
+		}
+		return value.Format(time.DateOnly)
+	}
+
+	fraction := ""
+	if decimals > 0 {
+		fraction = "." + strings.Repeat("0", int(decimals))
+	}
+
+	if value.IsZero() {
+		return "0000-00-00 00:00:00" + fraction
+	}
+
+	return value.Format(time.DateTime + fraction)
+}
+
+// formatMySQLValue prints bit fields as numbers and binary data as hex, the
+// way the mysql client shows them with --binary-as-hex.
+func formatMySQLValue(declared string, value any) string {
+	data, ok := value.([]byte)
+	if !ok {
+		return formatSQLValue(value)
+	}
+
+	switch {
+	case declared == "BIT":
+		var bits uint64
+		for _, b := range data {
+			bits = bits<<8 | uint64(b)
+		}
+		return strconv.FormatUint(bits, 10)
+	case declared == "BINARY", declared == "VARBINARY", declared == "GEOMETRY",
+		strings.HasSuffix(declared, "BLOB"):
+		return "0x" + strings.ToUpper(hex.EncodeToString(data))
+	default:
+		return string(data)
+	}
+}
+
+func (mysqlDialect) changes() string {
+	return "SELECT ROW_COUNT()"
+}
+
+func (mysqlDialect) changeCount(statement string, result ResultSet) (int64, bool) {
+	return 0, false
+}
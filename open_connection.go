@@ -4,42 +4,44 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Set from the theme
 var (
-	modelStyle = lipgloss.
-			NewStyle().
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color(GREY))
-	focusedModelStyle = lipgloss.
-				NewStyle().
-				BorderStyle(lipgloss.NormalBorder()).
-				BorderForeground(lipgloss.Color(WHITE))
-
-	focusedModelSideBarStyle = lipgloss.
-					NewStyle().
-					BorderStyle(lipgloss.NormalBorder()).
-					BorderForeground(lipgloss.Color(WHITE))
-
-	blurredModelSideBarStyle = lipgloss.
-					NewStyle().
-					Foreground(lipgloss.Color(GREY))
-	selectedTableStyle = lipgloss.
-				NewStyle().
-				Foreground(lipgloss.Color(MAGENTA))
+	modelStyle        lipgloss.Style
+	focusedModelStyle lipgloss.Style
+
+	blurredModelSideBarStyle lipgloss.Style
+	selectedTableStyle       lipgloss.Style
+
+	activeTxStyle lipgloss.Style
+	readOnlyStyle lipgloss.Style
+	failedTxStyle lipgloss.Style
 )
 
+const editorHeight = 5
+
 type ViewMode string
 
 const (
-	TABLES ViewMode = "TABLES"
-	OPEN   ViewMode = "OPEN"
-	QUIT   ViewMode = "QUIT"
+	TABLES  ViewMode = "TABLES"
+	OPEN    ViewMode = "OPEN"
+	QUERY   ViewMode = "QUERY"
+	HISTORY ViewMode = "HISTORY"
+	SAVED   ViewMode = "SAVED"
+	PARAMS  ViewMode = "PARAMS"
+	DDL     ViewMode = "DDL"
+	EXPLAIN ViewMode = "EXPLAIN"
+	CONFIRM ViewMode = "CONFIRM"
+	IMPORT  ViewMode = "IMPORT"
+	QUIT    ViewMode = "QUIT"
 )
 
 type tableItem string
@@ -68,61 +70,264 @@ func (d tableItemDelegate) Render(w io.Writer, m list.Model, index int, listItem
 }
 
 type OpenDatabase struct {
-	tables        list.Model
-	viewMode      ViewMode
-	selectedTable table.Model
-	params        Connection
+	tables         list.Model
+	viewMode       ViewMode
+	selectedTable  table.Model
+	params         Connection
+	tunnel         *Tunnel
+	session        *Session
+	savepoints     []string
+	confirmQuit    bool
+	rollbackFailed bool
+	importModel    ImportModel
+	history        HistoryModel
+	savedQueries   SavedQueriesModel
+	bindParams     ParamsModel
+	paramsFrom     ViewMode
+	confirm        ConfirmModel
+	confirmFrom    ViewMode
+	pending        []scriptStatement
+	pendingValues  map[string]*string
+	ddl            DDLModel
+	explain        ExplainModel
+	explainParams  bool
+	editor         QueryEditor
+	status         string
+	err            error
+	lastSQL        string
+	script         []scriptStatement
+	results        []StatementResult
+	resultIndex    int
+	stopOnError    bool
+	showHelp       bool
 }
 
 func NewOpenDatabase(connParams Connection) OpenDatabase {
-	databaseTables := connParams.GetTableNames()
-
-	listItems := []list.Item{}
-	for _, value := range databaseTables {
-		listItems = append(listItems, tableItem(value))
-	}
-
 	openDatabase := OpenDatabase{
-		tables:   list.New(listItems, tableItemDelegate{}, 14, 14),
-		viewMode: TABLES,
-		params:   connParams,
+		tables:      list.New([]list.Item{}, tableItemDelegate{}, 14, cfg.PageSize),
+		viewMode:    TABLES,
+		params:      connParams,
+		editor:      NewQueryEditor(),
+		stopOnError: true,
 	}
 
 	openDatabase.tables.SetShowHelp(false)
 	openDatabase.tables.SetShowTitle(false)
 	openDatabase.tables.SetShowStatusBar(false)
 
+	// Everything in the view connects through the tunnel until it is closed.
+	// Without it nothing connects, the database is only reachable through it.
+	if connParams.server() && connParams.SSHHost != "" {
+		tunnel, err := connParams.OpenTunnel()
+		if err != nil {
+			openDatabase.err = err
+			return openDatabase
+		}
+		openDatabase.tunnel = tunnel
+		openDatabase.params.tunnelAddr = tunnel.Addr()
+	}
+
+	listItems := []list.Item{}
+	for _, value := range openDatabase.params.GetTableNames() {
+		listItems = append(listItems, tableItem(value))
+	}
+	openDatabase.tables.SetItems(listItems)
+
 	openDatabase.setOpenTable()
+	openDatabase.loadCatalog()
 
 	return openDatabase
 }
 
+// connect opens the session connection, or reopens it after it was lost.
+// Whatever transaction it had is gone with it.
+func (db *OpenDatabase) connect() error {
+	if !db.session.Closed() {
+		return nil
+	}
+
+	session, err := db.params.OpenSession()
+	if err != nil {
+		return err
+	}
+
+	db.session = session
+	db.savepoints = nil
+	return nil
+}
+
+func (db OpenDatabase) Close() {
+	db.session.Close()
+	db.tunnel.Close()
+}
+
+// syncTx forgets the savepoints once the session is no longer in a
+// transaction, whether it ended from the keys or a statement in the editor.
+func (db *OpenDatabase) syncTx() {
+	if db.session.TxStatus() == TX_IDLE {
+		db.savepoints = nil
+		db.confirmQuit = false
+	}
+}
+
+type TxAction string
+
+const (
+	TX_BEGIN              TxAction = "BEGIN"
+	TX_COMMIT             TxAction = "COMMIT"
+	TX_ROLLBACK           TxAction = "ROLLBACK"
+	TX_SAVEPOINT          TxAction = "SAVEPOINT"
+	TX_ROLLBACK_SAVEPOINT TxAction = "ROLLBACK_SAVEPOINT"
+)
+
+func (db *OpenDatabase) transaction(action TxAction) {
+	if err := db.connect(); err != nil {
+		db.err = err
+		return
+	}
+
+	status := db.session.TxStatus()
+	var err error
+
+	switch action {
+	case TX_BEGIN:
+		if status != TX_IDLE {
+			db.status = "Already in a transaction"
+			return
+		}
+		err = db.session.Begin()
+		db.status = "BEGIN"
+
+	case TX_COMMIT:
+		if status == TX_IDLE {
+			db.status = "No transaction to commit"
+			return
+		}
+		// COMMIT of a failed transaction rolls it back
+		err = db.session.Commit()
+		db.status = "COMMIT"
+		if status == TX_FAILED {
+			db.status = "Failed transaction rolled back"
+		}
+
+	case TX_ROLLBACK:
+		if status == TX_IDLE {
+			db.status = "No transaction to roll back"
+			return
+		}
+		err = db.session.Rollback()
+		db.status = "ROLLBACK"
+
+	case TX_SAVEPOINT:
+		if status == TX_IDLE {
+			db.status = "Savepoints need a transaction, " + keyHelp(databaseKeys.Begin)
+			return
+		}
+		name := fmt.Sprintf("sp%d", len(db.savepoints)+1)
+		if err = db.session.Savepoint(name); err == nil {
+			db.savepoints = append(db.savepoints, name)
+		}
+		db.status = "SAVEPOINT " + name
+
+	case TX_ROLLBACK_SAVEPOINT:
+		if len(db.savepoints) == 0 {
+			db.status = "No savepoint to roll back to"
+			return
+		}
+		name := db.savepoints[len(db.savepoints)-1]
+		err = db.session.RollbackTo(name)
+		db.status = "ROLLBACK TO SAVEPOINT " + name
+	}
+
+	db.err = err
+	if err != nil {
+		db.status = ""
+	}
+	db.syncTx()
+}
+
+// txView is the indicator of the header while the session has a transaction
+// open.
+func (db OpenDatabase) txView() string {
+	switch db.session.TxStatus() {
+	case TX_ACTIVE:
+		label := " in transaction "
+		if len(db.savepoints) > 0 {
+			label = fmt.Sprintf(" in transaction (%s) ", db.savepoints[len(db.savepoints)-1])
+		}
+		return activeTxStyle.Render(label)
+	case TX_FAILED:
+		return failedTxStyle.Render(" failed transaction ")
+	}
+
+	return ""
+}
+
+func (db *OpenDatabase) loadCatalog() {
+	catalog, err := db.params.LoadCatalog()
+	if err != nil {
+		db.err = fmt.Errorf("could not load catalog: %w", err)
+		return
+	}
+
+	db.editor.catalog = catalog
+}
+
+// refresh reloads the table names and the catalog used for completion.
+func (db *OpenDatabase) refresh() {
+	listItems := []list.Item{}
+	for _, value := range db.params.GetTableNames() {
+		listItems = append(listItems, tableItem(value))
+	}
+	db.tables.SetItems(listItems)
+
+	db.loadCatalog()
+	if db.err == nil {
+		db.status = "Refreshed catalog"
+	}
+}
+
 func (db *OpenDatabase) setOpenTable() {
 	selectedItem := db.tables.SelectedItem()
+	if selectedItem == nil {
+		return
+	}
 	tableName := string(selectedItem.(tableItem))
 
 	selectedTable, err := db.openTable(tableName)
 
 	if err != nil {
 		db.params.status = DISCONNECTED
+		db.err = err
 		return
 	}
 
 	db.selectedTable = selectedTable
-	db.selectedTable.SetWidth(width / 2)
-	db.selectedTable.SetHeight(height / 2)
+	db.results = nil
+	db.status = ""
+	db.err = nil
 }
 
-func (db OpenDatabase) openTable(tableName string) (table.Model, error) {
-	tableData, err := db.params.SelectAll(tableName)
+// openTable reads the table through the session so changes of an open
+// transaction are visible.
+func (db *OpenDatabase) openTable(tableName string) (table.Model, error) {
+	if err := db.connect(); err != nil {
+		return db.selectedTable, err
+	}
+
+	tableData, err := db.session.SelectAll(tableName)
 
 	if err != nil {
 		return db.selectedTable, err
 	}
 
-	max_len := db.selectedTable.Width() / len(tableData.fields)
+	return newResultTable(tableData), nil
+}
+
+func newResultTable(tableData Table) table.Model {
 	columns := make([]table.Column, len(tableData.fields))
 	for i, field := range tableData.fields {
+		max_len := width / 2 / len(tableData.fields)
 		columns[i] = table.Column{Title: field, Width: max_len}
 	}
 
@@ -136,21 +341,230 @@ func (db OpenDatabase) openTable(tableName string) (table.Model, error) {
 		table.WithColumns(columns),
 		table.WithRows(rows),
 		table.WithFocused(true),
+		table.WithWidth(width/2),
+		table.WithHeight(height/2),
 	)
 
 	s := table.DefaultStyles()
 	s.Header = s.Header.
 		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		BorderForeground(lipgloss.Color(cfg.Theme.Muted)).
 		BorderBottom(true).
 		Bold(false)
 	s.Selected = s.Selected.
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
+		Foreground(lipgloss.Color(cfg.Theme.RowForeground)).
+		Background(lipgloss.Color(cfg.Theme.RowBackground)).
 		Bold(false)
 	t.SetStyles(s)
 
-	return t, nil
+	return t
+}
+
+// runQuery executes a statement or a script of several statements, shows the
+// results in place of the open table and records them in the history of the
+// connection. Statements with placeholders first prompt for their values.
+func (db *OpenDatabase) runQuery(sql string) tea.Cmd {
+	statements, names, err := parseScript(sql, db.params.Placeholders())
+	if err != nil {
+		db.err = err
+		return nil
+	}
+
+	if len(statements) == 0 {
+		return nil
+	}
+
+	if len(names) > 0 {
+		db.script = statements
+		db.explainParams = false
+		db.paramsFrom = db.viewMode
+		db.viewMode = PARAMS
+		db.editor.Blur()
+
+		var cmd tea.Cmd
+		db.bindParams, cmd = NewParamsModel(sql, names)
+		return cmd
+	}
+
+	return db.runStatements(statements, nil)
+}
+
+// explainQuery shows the plan of the statement under the editor cursor,
+// prompting for its placeholders first.
+func (db *OpenDatabase) explainQuery() tea.Cmd {
+	if db.params.Kind() != POSTGRES {
+		db.status = fmt.Sprintf("Explain is not available for %s connections", db.params.Kind())
+		return nil
+	}
+
+	statement := statementAt(db.editor.Value(), db.editor.cursorOffset())
+	if statement == "" {
+		return nil
+	}
+
+	bound, names, err := bindParameters(statement, db.params.Placeholders())
+	if err != nil {
+		db.err = err
+		return nil
+	}
+
+	st := scriptStatement{statement: statement, sql: bound, names: names}
+	if len(names) > 0 {
+		db.script = []scriptStatement{st}
+		db.explainParams = true
+		db.paramsFrom = db.viewMode
+		db.viewMode = PARAMS
+		db.editor.Blur()
+
+		var cmd tea.Cmd
+		db.bindParams, cmd = NewParamsModel(statement, names)
+		return cmd
+	}
+
+	db.showExplain(st, nil)
+	return nil
+}
+
+func (db *OpenDatabase) showExplain(st scriptStatement, values map[string]*string) {
+	if err := db.connect(); err != nil {
+		db.err = err
+		return
+	}
+
+	db.viewMode = EXPLAIN
+	db.editor.Blur()
+	db.explain = NewExplainModel(db.session, st.statement, st.sql, st.args(values))
+}
+
+// runStatements runs the statements once the connection name is typed to
+// confirm any dangerous ones among them.
+func (db *OpenDatabase) runStatements(statements []scriptStatement, values map[string]*string) tea.Cmd {
+	reasons := dangerousStatements(statements)
+	if len(reasons) == 0 || !cfg.Confirm.confirmDangerous(db.params) {
+		db.executeStatements(statements, values)
+		return nil
+	}
+
+	db.pending = statements
+	db.pendingValues = values
+	db.confirmFrom = db.viewMode
+	db.viewMode = CONFIRM
+	db.editor.Blur()
+
+	var cmd tea.Cmd
+	db.confirm, cmd = NewConfirmModel(db.params.Name, reasons)
+	return cmd
+}
+
+func (db *OpenDatabase) executeStatements(statements []scriptStatement, values map[string]*string) {
+	if err := db.connect(); err != nil {
+		db.err = err
+		return
+	}
+	defer db.syncTx()
+
+	if len(statements) == 1 {
+		db.results = nil
+		db.execute(statements[0].statement, statements[0].sql, statements[0].args(values)...)
+		return
+	}
+
+	db.results = db.session.RunScript(statements, values, db.stopOnError)
+
+	// Show the first shown statement, or the last one if all succeeded
+	shown := -1
+	for i, result := range db.results {
+		if result.skipped {
+			continue
+		}
+
+		entry := HistoryEntry{
+			Statement: result.statement.statement,
+			Time:      result.start,
+			Duration:  result.duration,
+			Rows:      result.table.affected,
+		}
+		if result.err != nil {
+			entry.Error = result.err.Error()
+			if shown < 0 {
+				shown = i
+			}
+		}
+		AddHistoryEntry(db.params.Name, entry)
+	}
+
+	if shown < 0 {
+		shown = len(db.results) - 1
+	}
+	db.showResult(shown)
+}
+
+// showResult switches to the result tab of a statement of the last script.
+func (db *OpenDatabase) showResult(i int) {
+	db.resultIndex = i
+	result := db.results[i]
+
+	db.lastSQL = result.statement.sql
+	db.err = result.err
+	db.selectedTable = newResultTable(result.table)
+
+	switch {
+	case result.skipped:
+		db.status = fmt.Sprintf("Statement %d skipped after an error", i+1)
+	case result.err == nil:
+		db.status = fmt.Sprintf("Statement %d: %s in %s", i+1, result.table.command, result.duration.Round(time.Millisecond))
+	}
+}
+
+func (db OpenDatabase) resultTabsView() string {
+	var tabs []string
+	for i, result := range db.results {
+		label := fmt.Sprintf("%d %s", i+1, result.duration.Round(time.Millisecond))
+		style := successStyle
+		switch {
+		case result.skipped:
+			label = fmt.Sprintf("%d skipped", i+1)
+			style = blurredStyle
+		case result.err != nil:
+			label = fmt.Sprintf("%d error", i+1)
+			style = errorStyle
+		}
+
+		if i == db.resultIndex {
+			tabs = append(tabs, selectedTableStyle.Render("["+label+"]"))
+		} else {
+			tabs = append(tabs, style.Render(" "+label+" "))
+		}
+	}
+
+	return strings.Join(tabs, " ")
+}
+
+func (db *OpenDatabase) execute(statement string, sql string, args ...any) {
+	db.lastSQL = sql
+
+	start := time.Now()
+	tableData, err := db.session.Query(sql, args...)
+
+	entry := HistoryEntry{
+		Statement: strings.TrimSpace(statement),
+		Time:      start,
+		Duration:  time.Since(start),
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+		db.err = err
+	} else {
+		entry.Rows = tableData.affected
+		db.selectedTable = newResultTable(tableData)
+		db.status = fmt.Sprintf("%s in %s", tableData.command, entry.Duration.Round(time.Millisecond))
+		db.err = nil
+	}
+
+	if err := AddHistoryEntry(db.params.Name, entry); err != nil && db.err == nil {
+		db.err = fmt.Errorf("could not record history: %w", err)
+	}
 }
 
 func (db OpenDatabase) Init() tea.Cmd {
@@ -158,14 +572,253 @@ func (db OpenDatabase) Init() tea.Cmd {
 }
 
 func (db OpenDatabase) Update(msg tea.Msg) (OpenDatabase, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch db.viewMode {
+	case IMPORT:
+		db.importModel, cmd = db.importModel.Update(msg)
+		if db.importModel.back {
+			db.viewMode = TABLES
+			db.setOpenTable()
+		}
+		return db, cmd
+
+	case HISTORY:
+		db.history, cmd = db.history.Update(msg)
+		if db.history.selected != nil {
+			statement := db.history.selected.Statement
+			if db.history.rerun {
+				db.viewMode = OPEN
+				cmd = db.runQuery(statement)
+			} else {
+				db.viewMode = QUERY
+				db.editor.SetValue(statement)
+				cmd = db.editor.Focus()
+			}
+		} else if db.history.back {
+			db.viewMode = OPEN
+		}
+		return db, cmd
+
+	case SAVED:
+		db.savedQueries, cmd = db.savedQueries.Update(msg)
+		if db.savedQueries.selected != nil {
+			statement := db.savedQueries.selected.Statement
+			if db.savedQueries.run {
+				db.viewMode = OPEN
+				cmd = db.runQuery(statement)
+			} else {
+				db.viewMode = QUERY
+				db.editor.SetValue(statement)
+				cmd = db.editor.Focus()
+			}
+		} else if db.savedQueries.back {
+			if db.savedQueries.saveOnly {
+				db.viewMode = QUERY
+				db.status = db.savedQueries.status
+				cmd = db.editor.Focus()
+			} else {
+				db.viewMode = OPEN
+			}
+		}
+		return db, cmd
+
+	case PARAMS:
+		db.bindParams, cmd = db.bindParams.Update(msg)
+		if db.bindParams.submitted || db.bindParams.back {
+			db.viewMode = db.paramsFrom
+			if db.viewMode == QUERY {
+				cmd = db.editor.Focus()
+			}
+		}
+		if db.bindParams.submitted {
+			if db.explainParams {
+				db.showExplain(db.script[0], db.bindParams.values())
+			} else {
+				cmd = tea.Batch(cmd, db.runStatements(db.script, db.bindParams.values()))
+			}
+		}
+		return db, cmd
+
+	case CONFIRM:
+		db.confirm, cmd = db.confirm.Update(msg)
+		if db.confirm.confirmed || db.confirm.back {
+			db.viewMode = db.confirmFrom
+			if db.viewMode == QUERY {
+				cmd = db.editor.Focus()
+			}
+		}
+		if db.confirm.confirmed {
+			db.executeStatements(db.pending, db.pendingValues)
+		}
+		if db.confirm.back {
+			db.status = "Cancelled"
+		}
+		return db, cmd
+
+	case EXPLAIN:
+		db.explain, cmd = db.explain.Update(msg)
+		if db.explain.back {
+			db.viewMode = QUERY
+			cmd = db.editor.Focus()
+		}
+		return db, cmd
+
+	case DDL:
+		db.ddl, cmd = db.ddl.Update(msg)
+		if db.ddl.back {
+			db.viewMode = TABLES
+		}
+		return db, cmd
+
+	case QUERY:
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			// The help overlay takes the keys until it is closed
+			if db.showHelp {
+				if key.Matches(msg, editorKeys.Help, editorKeys.Results) {
+					db.showHelp = false
+				}
+				return db, nil
+			}
+
+			switch {
+			case key.Matches(msg, editorKeys.Help):
+				db.showHelp = true
+				return db, nil
+
+			case key.Matches(msg, editorKeys.Results):
+				if db.editor.completing() {
+					break
+				}
+				db.viewMode = OPEN
+				db.editor.Blur()
+				return db, nil
+
+			case key.Matches(msg, editorKeys.SaveQuery):
+				db.viewMode = SAVED
+				db.editor.Blur()
+				db.savedQueries, cmd = NewSaveQueryModel(db.params.Name, db.editor.Value())
+				return db, cmd
+
+			case key.Matches(msg, editorKeys.Run):
+				return db, db.runQuery(db.editor.Value())
+
+			case key.Matches(msg, editorKeys.OnError):
+				db.stopOnError = !db.stopOnError
+				return db, nil
+
+			case key.Matches(msg, editorKeys.Explain):
+				return db, db.explainQuery()
+			}
+		}
+
+		db.editor, cmd = db.editor.Update(msg)
+		return db, cmd
+	}
+
+	// The help overlay takes the keys until it is closed
+	if msg, ok := msg.(tea.KeyMsg); ok && db.showHelp {
+		if key.Matches(msg, databaseKeys.Help, databaseKeys.Quit) {
+			db.showHelp = false
+		}
+		return db, nil
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "q", "ctrl+c":
+		switch {
+		case key.Matches(msg, databaseKeys.Help):
+			db.showHelp = true
+			return db, nil
+
+		case key.Matches(msg, databaseKeys.Quit):
+			// Ask before leaving an open transaction, which is rolled back
+			if cfg.Confirm.QuitTransaction && db.session.TxStatus() != TX_IDLE && !db.confirmQuit {
+				db.confirmQuit = true
+				db.status = "A transaction is open. " + keyHelp(databaseKeys.Commit, databaseKeys.Rollback) +
+					" • " + databaseKeys.Quit.Help().Key + ": roll back and quit"
+				return db, nil
+			}
+			// A failed roll back is shown, quitting again leaves regardless
+			if db.session.TxStatus() != TX_IDLE && !db.rollbackFailed {
+				if err := db.session.Rollback(); err != nil {
+					db.rollbackFailed = true
+					db.err = fmt.Errorf("could not roll back the transaction, %s again to quit anyway: %w",
+						databaseKeys.Quit.Help().Key, err)
+					return db, nil
+				}
+			}
 			db.viewMode = QUIT
 			return db, nil
 
-		case "left", "right":
+		case key.Matches(msg, databaseKeys.Begin):
+			db.transaction(TX_BEGIN)
+			return db, nil
+		case key.Matches(msg, databaseKeys.Commit):
+			db.transaction(TX_COMMIT)
+			return db, nil
+		case key.Matches(msg, databaseKeys.Rollback):
+			db.transaction(TX_ROLLBACK)
+			return db, nil
+		case key.Matches(msg, databaseKeys.Savepoint):
+			db.transaction(TX_SAVEPOINT)
+			return db, nil
+		case key.Matches(msg, databaseKeys.RollbackSavepoint):
+			db.transaction(TX_ROLLBACK_SAVEPOINT)
+			return db, nil
+
+		case key.Matches(msg, databaseKeys.Import):
+			if db.params.ReadOnly {
+				db.status = "Import is disabled on a read-only connection"
+				return db, nil
+			}
+			if db.params.Kind() != POSTGRES {
+				db.status = fmt.Sprintf("Import is not available for %s connections", db.params.Kind())
+				return db, nil
+			}
+			if db.viewMode == TABLES && db.tables.SelectedItem() != nil {
+				db.viewMode = IMPORT
+				// The table list shows the public schema
+				db.importModel = NewImportModel(db.params, "public", string(db.tables.SelectedItem().(tableItem)))
+				return db, db.importModel.Init()
+			}
+
+		case key.Matches(msg, databaseKeys.DDL):
+			if db.tables.SelectedItem() != nil {
+				db.viewMode = DDL
+				db.ddl = NewDDLModel(db.params, string(db.tables.SelectedItem().(tableItem)))
+				return db, nil
+			}
+
+		case key.Matches(msg, databaseKeys.Editor):
+			db.viewMode = QUERY
+			return db, db.editor.Focus()
+
+		case key.Matches(msg, databaseKeys.History):
+			db.viewMode = HISTORY
+			db.history = NewHistoryModel(db.params.Name)
+			return db, nil
+
+		case key.Matches(msg, databaseKeys.Refresh):
+			db.refresh()
+			return db, nil
+
+		case key.Matches(msg, databaseKeys.Saved):
+			db.viewMode = SAVED
+			db.savedQueries = NewSavedQueriesModel(db.params.Name)
+			return db, nil
+
+		case key.Matches(msg, databaseKeys.NextResult, databaseKeys.PrevResult):
+			if db.viewMode == OPEN && len(db.results) > 1 {
+				step := 1
+				if key.Matches(msg, databaseKeys.PrevResult) {
+					step = len(db.results) - 1
+				}
+				db.showResult((db.resultIndex + step) % len(db.results))
+				return db, nil
+			}
+
+		case key.Matches(msg, databaseKeys.SwitchPane):
 			switch db.viewMode {
 			case TABLES:
 				db.viewMode = OPEN
@@ -175,11 +828,13 @@ func (db OpenDatabase) Update(msg tea.Msg) (OpenDatabase, tea.Cmd) {
 		}
 	}
 
-	var cmd tea.Cmd
-
 	switch db.viewMode {
 	case TABLES:
+		index := db.tables.Index()
 		db.tables, cmd = db.tables.Update(msg)
+		if db.tables.Index() != index {
+			db.setOpenTable()
+		}
 	case OPEN:
 		db.selectedTable, cmd = db.selectedTable.Update(msg)
 	}
@@ -188,21 +843,107 @@ func (db OpenDatabase) Update(msg tea.Msg) (OpenDatabase, tea.Cmd) {
 }
 
 func (db OpenDatabase) View() string {
-	s := fmt.Sprintf("%s / %s\n\n", db.params.Name, db.params.Database)
+	name := db.params.Name
+	if color := db.params.EnvColor(); color != "" {
+		name = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(color)).Render(name)
+	}
+	s := fmt.Sprintf("%s / %s", name, db.params.Database)
+	if tag := db.params.envTag(); tag != "" {
+		s += " " + tag
+	}
+	if db.params.ReadOnly {
+		s += " " + readOnlyStyle.Render(" read-only ")
+	}
+	if tx := db.txView(); tx != "" {
+		s += " " + tx
+	}
+	s += "\n\n"
 
-	tableLabels := db.tables.View()
+	switch db.viewMode {
+	case IMPORT:
+		return paginationStyle.Render(s + db.importModel.View())
+	case HISTORY:
+		return paginationStyle.Render(s + db.history.View())
+	case SAVED:
+		return paginationStyle.Render(s + db.savedQueries.View())
+	case PARAMS:
+		return paginationStyle.Render(s + db.bindParams.View())
+	case DDL:
+		return paginationStyle.Render(s + db.ddl.View())
+	case EXPLAIN:
+		return paginationStyle.Render(s + db.explain.View())
+	case CONFIRM:
+		return paginationStyle.Render(s + db.confirm.View())
+	}
+
+	if db.showHelp && db.viewMode == QUERY {
+		closeKey := key.NewBinding(key.WithHelp(editorKeys.Help.Help().Key+"/"+editorKeys.Results.Help().Key, "close"))
+		return paginationStyle.Render(s + helpOverlay(editorKeys, closeKey))
+	}
+	if db.showHelp {
+		closeKey := key.NewBinding(key.WithHelp(databaseKeys.Help.Help().Key+"/"+databaseKeys.Quit.Help().Key, "close"))
+		return paginationStyle.Render(s + helpOverlay(databaseKeys, closeKey))
+	}
 
-	db.setOpenTable()
+	frame, focusedFrame := db.params.frameStyles()
+	tableLabels := db.tables.View()
 	openTable := db.selectedTable.View()
+	if len(db.results) > 1 {
+		openTable = db.resultTabsView() + "\n" + openTable
+	}
 
-	if db.viewMode == TABLES {
+	switch db.viewMode {
+	case TABLES:
 		s += lipgloss.JoinHorizontal(lipgloss.Top,
-			focusedModelSideBarStyle.Render(tableLabels),
-			modelStyle.Render(openTable))
-	} else {
+			focusedFrame.Render(tableLabels),
+			frame.Render(openTable))
+	case QUERY:
+		editor := []string{focusedFrame.Render(db.editor.View())}
+		if db.editor.completing() {
+			editor = append(editor, frame.Render(db.editor.completionView()))
+		}
+		editor = append(editor, frame.Render(openTable))
+
+		s += lipgloss.JoinHorizontal(lipgloss.Top,
+			frame.Render(tableLabels),
+			lipgloss.JoinVertical(lipgloss.Left, editor...))
+	default:
 		s += lipgloss.JoinHorizontal(lipgloss.Top,
-			modelStyle.Render(tableLabels),
-			focusedModelStyle.Render(openTable))
+			frame.Render(tableLabels),
+			focusedFrame.Render(openTable))
+	}
+
+	if db.err != nil {
+		s += "\n" + errorView(db.err, db.lastSQL)
+	} else if db.status != "" {
+		s += "\n" + blurredStyle.Render(db.status)
+	}
+
+	keys := databaseKeys
+	if db.viewMode == QUERY {
+		onError := "stop"
+		if !db.stopOnError {
+			onError = "continue"
+		}
+		s += helpStyle.Render("\n" + keyHelp(editorKeys.Run, editorKeys.Explain, editorKeys.SaveQuery, editorKeys.Complete,
+			withHelp(editorKeys.OnError, "on error "+onError), editorKeys.Help, editorKeys.Results))
+	} else if len(db.results) > 1 {
+		s += helpStyle.Render("\n" + keyHelp(keys.NextResult, keys.Editor, keys.Saved, keys.History, keys.Help, keys.Quit))
+	} else if db.params.ReadOnly {
+		s += helpStyle.Render("\n" + keyHelp(keys.Editor, keys.Saved, keys.History, keys.DDL, keys.Refresh, keys.Help, keys.Quit))
+	} else {
+		s += helpStyle.Render("\n" + keyHelp(keys.Editor, keys.Saved, keys.History, keys.Import, keys.DDL, keys.Refresh, keys.Help, keys.Quit))
+	}
+
+	switch db.session.TxStatus() {
+	case TX_ACTIVE:
+		s += helpStyle.Render("\n" + keyHelp(keys.Commit, keys.Rollback, keys.Savepoint, keys.RollbackSavepoint))
+	case TX_FAILED:
+		s += helpStyle.Render("\n" + keyHelp(keys.Rollback))
+	default:
+		if db.viewMode != QUERY {
+			s += helpStyle.Render("\n" + keyHelp(keys.Begin))
+		}
 	}
 
 	return paginationStyle.Render(s)
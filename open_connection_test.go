@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExplainNeedsPostgres(t *testing.T) {
+	db := OpenDatabase{viewMode: QUERY, params: Connection{Driver: SQLITE, Database: "app.db"}}
+
+	db, cmd := db.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	if db.viewMode != QUERY || cmd != nil || db.session != nil {
+		t.Errorf("explain on sqlite: mode = %v, cmd = %v, connected = %v", db.viewMode, cmd != nil, db.session != nil)
+	}
+	if db.status != "Explain is not available for sqlite connections" {
+		t.Errorf("status = %q", db.status)
+	}
+}
+
+// brokenConn is a connection in a transaction whose statements fail.
+type brokenConn struct {
+	DriverConn
+}
+
+func (brokenConn) Exec(ctx context.Context, sql string) error { return errors.New("connection reset") }
+func (brokenConn) TxStatus() TxStatus                         { return TX_ACTIVE }
+func (brokenConn) Closed() bool                               { return false }
+
+func TestQuitShowsRollbackError(t *testing.T) {
+	db := OpenDatabase{viewMode: TABLES, session: &Session{conn: brokenConn{}}, confirmQuit: true}
+	quit := tea.KeyMsg{Type: tea.KeyCtrlC}
+
+	db, _ = db.Update(quit)
+	if db.viewMode == QUIT || db.err == nil {
+		t.Fatalf("quit after a failed roll back: mode = %v, err = %v", db.viewMode, db.err)
+	}
+
+	db, _ = db.Update(quit)
+	if db.viewMode != QUIT {
+		t.Errorf("quitting again: mode = %v", db.viewMode)
+	}
+}
+
+func TestOpenDatabaseWithoutServerSkipsTunnel(t *testing.T) {
+	params := Connection{Driver: SQLITE, Database: filepath.Join(t.TempDir(), "app.db")}
+	if err := os.WriteFile(params.Database, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sqliteDriver{}.Connect(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.Exec(context.Background(), "CREATE TABLE users (id INTEGER)")
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SSH settings left over from when the connection pointed at a server
+	t.Setenv("SSH_AUTH_SOCK", "")
+	params.SSHHost = "bastion.invalid"
+
+	db := NewOpenDatabase(params)
+	if db.err != nil || db.tunnel != nil {
+		t.Fatalf("err = %v, tunnel = %v", db.err, db.tunnel)
+	}
+	if items := db.tables.Items(); len(items) != 1 || items[0] != tableItem("users") {
+		t.Errorf("tables = %v", items)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	bolt "go.etcd.io/bbolt"
+)
+
+const PARAMS_BUCKET_NAME = "query_params"
+
+// bindParameters finds the placeholders of a statement. Named placeholders
+// (:name) are rewritten to positional ones of the style, $1 in order of first
+// use or a ? for every use. A statement using positional placeholders is
+// returned unchanged with names $1..$n or ?1..?n. The returned names are in
+// bind order. A ? is only a placeholder in the ? style, elsewhere it is the
+// jsonb operator.
+func bindParameters(sql string, style PlaceholderStyle) (string, []string, error) {
+	var b strings.Builder
+	var names []string
+	indexes := map[string]int{}
+	positional := 0
+	named := false
+
+	for _, token := range tokenizeSQL(sql) {
+		if token.kind != TOKEN_PARAM || (token.text == "?" && style != PLACEHOLDER_QUESTION) {
+			b.WriteString(token.text)
+			continue
+		}
+
+		switch {
+		case token.text == "?":
+			positional++
+			names = append(names, fmt.Sprintf("?%d", positional))
+			b.WriteString(token.text)
+
+		case strings.HasPrefix(token.text, "$"):
+			if style == PLACEHOLDER_QUESTION {
+				return "", nil, fmt.Errorf("invalid placeholder %s, use ? or :name", token.text)
+			}
+			n, err := strconv.Atoi(token.text[1:])
+			if err != nil || n == 0 {
+				return "", nil, fmt.Errorf("invalid placeholder %s", token.text)
+			}
+			positional = max(positional, n)
+			b.WriteString(token.text)
+
+		case style == PLACEHOLDER_QUESTION:
+			named = true
+			names = append(names, token.text[1:])
+			b.WriteString("?")
+
+		default:
+			named = true
+			name := token.text[1:]
+			if _, ok := indexes[name]; !ok {
+				names = append(names, name)
+				indexes[name] = len(names)
+			}
+			fmt.Fprintf(&b, "$%d", indexes[name])
+		}
+	}
+
+	if positional > 0 && named {
+		if style == PLACEHOLDER_QUESTION {
+			return "", nil, errors.New("cannot mix named (:name) and positional (?) placeholders")
+		}
+		return "", nil, errors.New("cannot mix named (:name) and positional ($1) placeholders")
+	}
+
+	if style == PLACEHOLDER_DOLLAR {
+		for i := 1; i <= positional; i++ {
+			names = append(names, fmt.Sprintf("$%d", i))
+		}
+	}
+
+	return b.String(), names, nil
+}
+
+func paramsKey(statement string) []byte {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(statement)))
+	return sum[:]
+}
+
+// LoadParamValues returns the values last used to run a statement. A nil
+// value was bound as NULL.
+func LoadParamValues(statement string) (map[string]*string, error) {
+	db, err := openLocalDb()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	values := map[string]*string{}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(PARAMS_BUCKET_NAME))
+		if b == nil {
+			return nil
+		}
+
+		v := b.Get(paramsKey(statement))
+		if v == nil {
+			return nil
+		}
+
+		return json.Unmarshal(v, &values)
+	})
+
+	return values, err
+}
+
+func SaveParamValues(statement string, values map[string]*string) error {
+	db, err := openLocalDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	value, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(PARAMS_BUCKET_NAME))
+		if err != nil {
+			return err
+		}
+
+		return b.Put(paramsKey(statement), value)
+	})
+}
+
+type ParamsModel struct {
+	statement  string
+	names      []string
+	inputs     []textinput.Model
+	nulls      []bool
+	focusIndex int
+	submitted  bool
+	back       bool
+	err        error
+}
+
+func NewParamsModel(statement string, names []string) (ParamsModel, tea.Cmd) {
+	m := ParamsModel{
+		statement: statement,
+		names:     names,
+		inputs:    make([]textinput.Model, len(names)),
+		nulls:     make([]bool, len(names)),
+	}
+
+	remembered, err := LoadParamValues(statement)
+	m.err = err
+
+	for i, name := range names {
+		t := textinput.New()
+		t.Cursor.Style = cursorStyle
+		t.Prompt = fmt.Sprintf("%s: ", name)
+		t.Placeholder = "value"
+
+		if value, ok := remembered[name]; ok {
+			if value == nil {
+				m.nulls[i] = true
+			} else {
+				t.SetValue(*value)
+			}
+		}
+
+		m.inputs[i] = t
+	}
+
+	return m, m.updateFocus()
+}
+
+// values returns the value of each placeholder, nil to bind NULL.
+func (m ParamsModel) values() map[string]*string {
+	values := map[string]*string{}
+	for i, name := range m.names {
+		if m.nulls[i] {
+			values[name] = nil
+			continue
+		}
+		value := m.inputs[i].Value()
+		values[name] = &value
+	}
+
+	return values
+}
+
+func (m *ParamsModel) updateFocus() tea.Cmd {
+	var cmd tea.Cmd
+	for i := range m.inputs {
+		if i == m.focusIndex {
+			cmd = m.inputs[i].Focus()
+			m.inputs[i].PromptStyle = focusedItemStyle
+			m.inputs[i].TextStyle = focusedItemStyle
+			continue
+		}
+		m.inputs[i].Blur()
+		m.inputs[i].PromptStyle = noStyle
+		m.inputs[i].TextStyle = noStyle
+	}
+
+	return cmd
+}
+
+func (m ParamsModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m ParamsModel) Update(msg tea.Msg) (ParamsModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, promptKeys.Cancel):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, promptKeys.Submit):
+			if err := SaveParamValues(m.statement, m.values()); err != nil {
+				m.err = err
+			}
+			m.submitted = true
+			return m, nil
+
+		// Toggle binding NULL instead of the typed value
+		case key.Matches(msg, paramsKeys.Null):
+			m.nulls[m.focusIndex] = !m.nulls[m.focusIndex]
+			return m, nil
+
+		case key.Matches(msg, promptKeys.NextField, promptKeys.PrevField):
+			if key.Matches(msg, promptKeys.PrevField) {
+				m.focusIndex--
+			} else {
+				m.focusIndex++
+			}
+
+			if m.focusIndex >= len(m.inputs) {
+				m.focusIndex = 0
+			} else if m.focusIndex < 0 {
+				m.focusIndex = len(m.inputs) - 1
+			}
+
+			return m, m.updateFocus()
+		}
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focusIndex], cmd = m.inputs[m.focusIndex].Update(msg)
+	return m, cmd
+}
+
+func (m ParamsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString("Query parameters\n\n")
+
+	for i := range m.inputs {
+		if m.nulls[i] {
+			style := noStyle
+			if i == m.focusIndex {
+				style = focusedItemStyle
+			}
+			b.WriteString(style.Render(m.inputs[i].Prompt) + blurredStyle.Render("NULL"))
+		} else {
+			b.WriteString(m.inputs[i].View())
+		}
+		b.WriteRune('\n')
+	}
+
+	b.WriteString(helpStyle.Render("\n" + keyHelp(withHelp(promptKeys.Submit, "run"), withHelp(promptKeys.NextField, "next"),
+		paramsKeys.Null, promptKeys.Cancel)))
+
+	if m.err != nil {
+		b.WriteString("\n" + errorStyle.Render(m.err.Error()))
+	}
+
+	return b.String()
+}
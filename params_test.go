@@ -0,0 +1,84 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindParameters(t *testing.T) {
+	tests := []struct {
+		sql   string
+		style PlaceholderStyle
+		want  string
+		names []string
+		err   bool
+	}{
+		{sql: "select 1", want: "select 1"},
+		{
+			sql:   "select * from t where a = :a and b = :b or a = :a",
+			want:  "select * from t where a = $1 and b = $2 or a = $1",
+			names: []string{"a", "b"},
+		},
+		{
+			sql:   "select $2, $1",
+			want:  "select $2, $1",
+			names: []string{"$1", "$2"},
+		},
+		{
+			sql:  "select ':a', \":a\", $$ :a $$ -- :a",
+			want: "select ':a', \":a\", $$ :a $$ -- :a",
+		},
+		{
+			sql:   "select a::int, arr[1:2] from t where id = :id",
+			want:  "select a::int, arr[1:2] from t where id = $1",
+			names: []string{"id"},
+		},
+		{sql: "select :a, $1", err: true},
+		{sql: "select $0", err: true},
+		{
+			sql:  "select data ? 'key', data ?| array['a'] from t",
+			want: "select data ? 'key', data ?| array['a'] from t",
+		},
+		{
+			sql:   "select * from t where a = :a and b = :b or a = :a",
+			style: PLACEHOLDER_QUESTION,
+			want:  "select * from t where a = ? and b = ? or a = ?",
+			names: []string{"a", "b", "a"},
+		},
+		{
+			sql:   "select * from `order` where id=? and note = '?'",
+			style: PLACEHOLDER_QUESTION,
+			want:  "select * from `order` where id=? and note = '?'",
+			names: []string{"?1"},
+		},
+		{
+			sql:   "insert into t values (?, ?)",
+			style: PLACEHOLDER_QUESTION,
+			want:  "insert into t values (?, ?)",
+			names: []string{"?1", "?2"},
+		},
+		{sql: "select ?, :a", style: PLACEHOLDER_QUESTION, err: true},
+		{sql: "select $1", style: PLACEHOLDER_QUESTION, err: true},
+	}
+
+	for _, test := range tests {
+		if test.style == "" {
+			test.style = PLACEHOLDER_DOLLAR
+		}
+
+		got, names, err := bindParameters(test.sql, test.style)
+		if test.err {
+			if err == nil {
+				t.Errorf("bindParameters(%q) succeeded, want an error", test.sql)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("bindParameters(%q): %v", test.sql, err)
+			continue
+		}
+		if got != test.want || !reflect.DeepEqual(names, test.names) {
+			t.Errorf("bindParameters(%q) = %q, %v, want %q, %v", test.sql, got, names, test.want, test.names)
+		}
+	}
+}
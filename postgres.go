@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type postgresDriver struct{}
+
+func (postgresDriver) Connect(params Connection) (DriverConn, error) {
+	conn, err := params.Connect()
+	if err != nil {
+		return nil, describeConnectError(err)
+	}
+
+	return &postgresConn{conn: conn}, nil
+}
+
+type postgresConn struct {
+	conn *pgx.Conn
+}
+
+func (c *postgresConn) Tables() ([]string, error) {
+	rows, err := c.conn.Query(context.Background(),
+		"SELECT table_name FROM information_schema.tables WHERE table_schema='public'")
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (c *postgresConn) Columns(table string) ([]Column, error) {
+	return postgresColumns(c.conn, "public", table)
+}
+
+// postgresColumns describes the columns of a table in a schema in order.
+func postgresColumns(conn *pgx.Conn, schema string, table string) ([]Column, error) {
+	rows, err := conn.Query(context.Background(),
+		`SELECT column_name, data_type, is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2
+		ORDER BY ordinal_position`, schema, table)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		err = rows.Scan(&column.Name, &column.DataType, &column.Nullable)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+func (c *postgresConn) Query(ctx context.Context, sql string, args ...any) (Table, error) {
+	rows, err := c.conn.Query(ctx, sql, args...)
+
+	if err != nil {
+		return Table{}, err
+	}
+
+	return readTable(rows)
+}
+
+func readTable(rows pgx.Rows) (Table, error) {
+	defer rows.Close()
+
+	var tableData Table
+
+	fieldDescriptions := rows.FieldDescriptions()
+	tableData.fields = make([]string, len(fieldDescriptions))
+	for i, field := range fieldDescriptions {
+		tableData.fields[i] = field.Name
+	}
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return Table{}, err
+		}
+
+		strValues := make([]string, len(values))
+
+		for i, value := range values {
+			strValues[i] = displayValue(value, fieldDescriptions[i].DataTypeOID == pgtype.DateOID)
+		}
+
+		tableData.values = append(tableData.values, strValues)
+	}
+
+	if err := rows.Err(); err != nil {
+		return Table{}, err
+	}
+
+	tableData.command = rows.CommandTag().String()
+	tableData.affected = rows.CommandTag().RowsAffected()
+
+	return tableData, nil
+}
+
+// QueryText asks the server for text results so every type is printed the
+// way postgres formats it.
+func (c *postgresConn) QueryText(ctx context.Context, sql string) (ResultSet, error) {
+	rows, err := c.conn.Query(ctx, sql, pgx.QueryResultFormats{pgx.TextFormatCode})
+	if err != nil {
+		return ResultSet{}, err
+	}
+	defer rows.Close()
+
+	var result ResultSet
+	for _, field := range rows.FieldDescriptions() {
+		result.fields = append(result.fields, field.Name)
+		result.kinds = append(result.kinds, postgresValueKind(field.DataTypeOID))
+	}
+
+	for rows.Next() {
+		raw := rows.RawValues()
+		values := make([]*string, len(raw))
+		for i, value := range raw {
+			if value != nil {
+				text := string(value)
+				values[i] = &text
+			}
+		}
+		result.values = append(result.values, values)
+	}
+
+	if err := rows.Err(); err != nil {
+		return ResultSet{}, err
+	}
+
+	result.command = rows.CommandTag().String()
+	return result, nil
+}
+
+func postgresValueKind(oid uint32) ValueKind {
+	switch oid {
+	case pgtype.BoolOID:
+		return VALUE_BOOL
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID, pgtype.OIDOID,
+		pgtype.Float4OID, pgtype.Float8OID, pgtype.NumericOID:
+		return VALUE_NUMBER
+	case pgtype.JSONOID, pgtype.JSONBOID:
+		return VALUE_JSON
+	default:
+		return VALUE_TEXT
+	}
+}
+
+func (c *postgresConn) Exec(ctx context.Context, sql string) error {
+	_, err := c.conn.Exec(ctx, sql)
+	return err
+}
+
+// Cancel asks the server to cancel the running statement over a separate
+// connection, leaving this one usable.
+func (c *postgresConn) Cancel() error {
+	return c.conn.PgConn().CancelRequest(context.Background())
+}
+
+func (c *postgresConn) TxStatus() TxStatus {
+	return TxStatus(c.conn.PgConn().TxStatus())
+}
+
+func (c *postgresConn) Closed() bool {
+	return c.conn.IsClosed()
+}
+
+func (c *postgresConn) Close() {
+	c.conn.Close(context.Background())
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type OutputFormat string
+
+const (
+	FORMAT_TABLE OutputFormat = "table"
+	FORMAT_CSV   OutputFormat = "csv"
+	FORMAT_JSON  OutputFormat = "json"
+)
+
+type ValueKind string
+
+const (
+	VALUE_TEXT   ValueKind = "TEXT"
+	VALUE_NUMBER ValueKind = "NUMBER"
+	VALUE_BOOL   ValueKind = "BOOL"
+	VALUE_JSON   ValueKind = "JSON"
+)
+
+// ResultSet holds rows in the text format of the database, as its own shell
+// prints them, with the kind of each column for json output. A nil value is
+// NULL.
+type ResultSet struct {
+	fields  []string
+	kinds   []ValueKind
+	values  [][]*string
+	command string
+}
+
+// jsonValue keeps numbers, booleans and json columns native and quotes the
+// rest. Values such as NaN that are not valid json stay strings.
+func jsonValue(kind ValueKind, value *string) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+
+	switch kind {
+	case VALUE_BOOL:
+		switch strings.ToLower(*value) {
+		case "t", "true", "1":
+			return json.RawMessage("true")
+		case "f", "false", "0":
+			return json.RawMessage("false")
+		}
+
+	case VALUE_NUMBER, VALUE_JSON:
+		if json.Valid([]byte(*value)) {
+			return json.RawMessage(*value)
+		}
+	}
+
+	quoted, _ := json.Marshal(*value)
+	return quoted
+}
+
+func (r ResultSet) writeJSON(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("[")
+	for i, row := range r.values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, field := range r.fields {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			name, _ := json.Marshal(field)
+			b.Write(name)
+			b.WriteString(": ")
+			b.Write(jsonValue(r.kinds[j], row[j]))
+		}
+		b.WriteString("}")
+	}
+	if len(r.values) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCSV writes NULL as an empty field, as COPY ... CSV does.
+func (r ResultSet) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(r.fields); err != nil {
+		return err
+	}
+
+	for _, row := range r.values {
+		record := make([]string, len(row))
+		for i, value := range row {
+			if value != nil {
+				record[i] = *value
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTable aligns the rows in columns under a header like psql.
+func (r ResultSet) writeTable(w io.Writer) error {
+	cells := make([][]string, len(r.values))
+	widths := make([]int, len(r.fields))
+	for i, field := range r.fields {
+		widths[i] = lipgloss.Width(field)
+	}
+
+	for i, row := range r.values {
+		cells[i] = make([]string, len(row))
+		for j, value := range row {
+			text := "NULL"
+			if value != nil {
+				text = strings.ReplaceAll(*value, "\n", `\n`)
+			}
+			cells[i][j] = text
+			widths[j] = max(widths[j], lipgloss.Width(text))
+		}
+	}
+
+	pad := func(s string, width int) string {
+		return s + strings.Repeat(" ", width-lipgloss.Width(s))
+	}
+
+	var b strings.Builder
+	line := func(row []string) {
+		padded := make([]string, len(row))
+		for i, cell := range row {
+			padded[i] = pad(cell, widths[i])
+		}
+		b.WriteString(strings.TrimRight(strings.Join(padded, " | "), " ") + "\n")
+	}
+
+	line(r.fields)
+	for i, width := range widths {
+		if i > 0 {
+			b.WriteString("-+-")
+		}
+		b.WriteString(strings.Repeat("-", width))
+	}
+	b.WriteString("\n")
+
+	for _, row := range cells {
+		line(row)
+	}
+
+	if len(r.values) == 1 {
+		b.WriteString("(1 row)\n")
+	} else {
+		fmt.Fprintf(&b, "(%d rows)\n", len(r.values))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeJSONScript writes the results of several statements as one json array
+// holding the array of rows of each statement that returned rows.
+func writeJSONScript(w io.Writer, results []ResultSet) error {
+	var b strings.Builder
+
+	b.WriteString("[")
+	for i, result := range results {
+		var rows strings.Builder
+		if err := result.writeJSON(&rows); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n" + strings.TrimSuffix(rows.String(), "\n"))
+	}
+	if len(results) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Write prints the rows in the format. Statements that return no rows, such
+// as an INSERT, only report their command tag on stderr.
+func (r ResultSet) Write(w io.Writer, format OutputFormat) error {
+	if len(r.fields) == 0 {
+		fmt.Fprintln(os.Stderr, r.command)
+		return nil
+	}
+
+	switch format {
+	case FORMAT_CSV:
+		return r.writeCSV(w)
+	case FORMAT_JSON:
+		return r.writeJSON(w)
+	default:
+		return r.writeTable(w)
+	}
+}
+
+// readQuerySQL takes the SQL from -c, a file, or stdin when neither is given
+// and it is not a terminal. A file of "-" also reads stdin.
+func readQuerySQL(command string, file string) (string, error) {
+	switch {
+	case command != "" && file != "":
+		return "", errors.New("-c and -f cannot be used together")
+
+	case command != "":
+		return command, nil
+
+	case file == "-":
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+
+	case file != "":
+		data, err := os.ReadFile(expandHome(file))
+		return string(data), err
+	}
+
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeCharDevice != 0 {
+		return "", errors.New("no SQL given, use -c, -f or pipe it to stdin")
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	return string(data), err
+}
+
+func queryCommand(args []string) int {
+	fs := newFlagSet("query")
+	command := fs.String("c", "", "SQL to run")
+	file := fs.String("f", "", "file of SQL to run, - for stdin")
+	url := fs.String("url", "", "postgres://, mysql://, sqlite: or duckdb: URL, or \"host=... dbname=...\" settings instead of a saved connection")
+	format := fs.String("format", string(FORMAT_TABLE), "output format: table, csv or json")
+	yes := fs.Bool("yes", false, "run DROP, TRUNCATE and DELETE or UPDATE without WHERE")
+
+	help := func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: termtable query [flags] <name>")
+		fmt.Fprintln(w, "       termtable query --url <url> [flags]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Runs the SQL without the interface, stopping at the first failing statement.")
+		fmt.Fprintln(w, "With --format json several statements print one array holding an array of")
+		fmt.Fprintln(w, "rows for each statement that returns rows.")
+		fmt.Fprintln(w)
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+		fs.SetOutput(os.Stderr)
+	}
+
+	names, err := parseArgs(fs, args)
+	if err != nil {
+		return flagError(err, help)
+	}
+	if (*url == "") == (len(names) == 0) || len(names) > 1 {
+		help(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	switch OutputFormat(*format) {
+	case FORMAT_TABLE, FORMAT_CSV, FORMAT_JSON:
+	default:
+		fmt.Fprintf(os.Stderr, "termtable: unknown format %q\n", *format)
+		return EXIT_USAGE
+	}
+
+	sql, err := readQuerySQL(*command, *file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_USAGE
+	}
+
+	statements := splitStatements(sql)
+	if len(statements) == 0 {
+		fmt.Fprintln(os.Stderr, "termtable: no statements to run")
+		return EXIT_USAGE
+	}
+
+	var conn Connection
+	if *url != "" {
+		conn, err = ParseConnectionString(*url)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "termtable: --url:", err)
+			return EXIT_USAGE
+		}
+		conn = conn.withDefaults()
+	} else {
+		conn, err = LoadConnection(names[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "termtable:", err)
+			return EXIT_ERROR
+		}
+	}
+
+	if !*yes && cfg.Confirm.confirmDangerous(conn) {
+		for i, statement := range statements {
+			if reason := dangerousStatement(statement); reason != "" {
+				fmt.Fprintf(os.Stderr, "termtable: statement %d is a %s, pass --yes to run it\n", i+1, reason)
+				return EXIT_ERROR
+			}
+		}
+	}
+
+	if err := conn.validateSSL(); err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	if conn.server() && conn.SSHHost != "" {
+		tunnel, err := conn.OpenTunnel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "termtable:", err)
+			return EXIT_ERROR
+		}
+		defer tunnel.Close()
+
+		conn.tunnelAddr = tunnel.Addr()
+	}
+
+	session, err := conn.OpenSession()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+	defer session.Close()
+
+	// Interrupting cancels the running statement, which then fails as usual
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer func() {
+		signal.Stop(interrupt)
+		close(interrupt)
+	}()
+	go func() {
+		for range interrupt {
+			session.Cancel()
+		}
+	}()
+
+	// The rows of a json script are written together, including those of the
+	// statements run before one failed
+	jsonScript := OutputFormat(*format) == FORMAT_JSON && len(statements) > 1
+	var results []ResultSet
+
+	code := EXIT_OK
+	for i, statement := range statements {
+		result, err := session.QueryText(statement)
+		if err != nil {
+			if len(statements) > 1 {
+				fmt.Fprintf(os.Stderr, "termtable: statement %d: %v\n", i+1, err)
+			} else {
+				fmt.Fprintln(os.Stderr, "termtable:", err)
+			}
+			code = EXIT_ERROR
+			break
+		}
+
+		if jsonScript && len(result.fields) > 0 {
+			results = append(results, result)
+			continue
+		}
+
+		if i > 0 && len(result.fields) > 0 {
+			fmt.Println()
+		}
+
+		if err := result.Write(os.Stdout, OutputFormat(*format)); err != nil {
+			fmt.Fprintln(os.Stderr, "termtable:", err)
+			return EXIT_ERROR
+		}
+	}
+
+	if jsonScript {
+		if err := writeJSONScript(os.Stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, "termtable:", err)
+			return EXIT_ERROR
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func text(s string) *string {
+	return &s
+}
+
+// testResult has a NULL, a value with a newline, a wide character and a
+// number that is not valid json.
+var testResult = ResultSet{
+	fields: []string{"id", "name", "active", "data"},
+	kinds:  []ValueKind{VALUE_NUMBER, VALUE_TEXT, VALUE_BOOL, VALUE_JSON},
+	values: [][]*string{
+		{text("1"), text("Zoë"), text("t"), text(`{"a": 1}`)},
+		{text("NaN"), text("two\nlines"), text("f"), nil},
+	},
+	command: "SELECT 2",
+}
+
+func TestWriteTable(t *testing.T) {
+	var b strings.Builder
+	if err := testResult.writeTable(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "" +
+		"id  | name       | active | data\n" +
+		"----+------------+--------+---------\n" +
+		"1   | Zoë        | t      | {\"a\": 1}\n" +
+		"NaN | two\\nlines | f      | NULL\n" +
+		"(2 rows)\n"
+	if b.String() != want {
+		t.Errorf("writeTable()\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	one := ResultSet{fields: []string{"n"}, kinds: []ValueKind{VALUE_NUMBER}, values: [][]*string{{text("1")}}}
+	if err := one.writeTable(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "n\n-\n1\n(1 row)\n"; b.String() != want {
+		t.Errorf("writeTable() of one row = %q, want %q", b.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	if err := testResult.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[\n" +
+		"  {\"id\": 1, \"name\": \"Zoë\", \"active\": true, \"data\": {\"a\": 1}},\n" +
+		"  {\"id\": \"NaN\", \"name\": \"two\\nlines\", \"active\": false, \"data\": null}\n" +
+		"]\n"
+	if b.String() != want {
+		t.Errorf("writeJSON()\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	empty := ResultSet{fields: []string{"id"}, kinds: []ValueKind{VALUE_NUMBER}}
+	if err := empty.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("writeJSON() of no rows = %q", b.String())
+	}
+}
+
+func TestWriteJSONScript(t *testing.T) {
+	one := ResultSet{fields: []string{"n"}, kinds: []ValueKind{VALUE_NUMBER}, values: [][]*string{{text("1")}}}
+	empty := ResultSet{fields: []string{"n"}, kinds: []ValueKind{VALUE_NUMBER}}
+
+	var b strings.Builder
+	if err := writeJSONScript(&b, []ResultSet{one, empty, testResult}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, b.String())
+	}
+	if len(got) != 3 || len(got[0]) != 1 || len(got[1]) != 0 || len(got[2]) != 2 {
+		t.Errorf("writeJSONScript() = %v", got)
+	}
+
+	b.Reset()
+	if err := writeJSONScript(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("writeJSONScript() of no results = %q", b.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b strings.Builder
+	if err := testResult.writeCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,name,active,data\n" +
+		"1,Zoë,t,\"{\"\"a\"\": 1}\"\n" +
+		"NaN,\"two\nlines\",f,\n"
+	if b.String() != want {
+		t.Errorf("writeCSV()\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	tests := []struct {
+		kind  ValueKind
+		value *string
+		want  string
+	}{
+		{kind: VALUE_TEXT, value: nil, want: "null"},
+		{kind: VALUE_TEXT, value: text("42"), want: `"42"`},
+		{kind: VALUE_NUMBER, value: text("-1.5e3"), want: "-1.5e3"},
+		{kind: VALUE_NUMBER, value: text("Infinity"), want: `"Infinity"`},
+		{kind: VALUE_BOOL, value: text("1"), want: "true"},
+		{kind: VALUE_BOOL, value: text("yes"), want: `"yes"`},
+		{kind: VALUE_JSON, value: text(`[1, "a"]`), want: `[1, "a"]`},
+		{kind: VALUE_JSON, value: text(`{broken`), want: `"{broken"`},
+	}
+
+	for _, test := range tests {
+		if got := string(jsonValue(test.kind, test.value)); got != test.want {
+			t.Errorf("jsonValue(%s, %v) = %s, want %s", test.kind, test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	SAVED_QUERIES_BUCKET_NAME = "saved_queries"
+
+	globalScopeBucket     = "global"
+	connectionScopePrefix = "connection:"
+
+	defaultExportPath = "queries.sql"
+)
+
+type SavedQuery struct {
+	Name      string `json:"name"`
+	Folder    string `json:"folder,omitempty"`
+	Statement string `json:"statement"`
+	// Scope is the name of the connection the query belongs to, empty for
+	// queries shared by all connections
+	Scope string `json:"scope,omitempty"`
+}
+
+func (q SavedQuery) Path() string {
+	if q.Folder == "" {
+		return q.Name
+	}
+	return q.Folder + "/" + q.Name
+}
+
+// parseSavedQueryPath splits "folder/sub/name" into its folder and name.
+func parseSavedQueryPath(path string) (string, string) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+func scopeBucketName(scope string) []byte {
+	if scope == "" {
+		return []byte(globalScopeBucket)
+	}
+	return []byte(connectionScopePrefix + scope)
+}
+
+func SaveQuery(q SavedQuery) error {
+	if q.Name == "" {
+		return errors.New("saved query needs a name")
+	}
+
+	db, err := openLocalDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	value, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		saved, err := tx.CreateBucketIfNotExists([]byte(SAVED_QUERIES_BUCKET_NAME))
+		if err != nil {
+			return err
+		}
+
+		b, err := saved.CreateBucketIfNotExists(scopeBucketName(q.Scope))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(q.Path()), value)
+	})
+}
+
+func DeleteSavedQuery(q SavedQuery) error {
+	db, err := openLocalDb()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		saved := tx.Bucket([]byte(SAVED_QUERIES_BUCKET_NAME))
+		if saved == nil {
+			return nil
+		}
+
+		b := saved.Bucket(scopeBucketName(q.Scope))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(q.Path()))
+	})
+}
+
+// ListSavedQueries returns the global queries and the queries of the
+// connection, ordered by folder and name.
+func ListSavedQueries(connName string) ([]SavedQuery, error) {
+	db, err := openLocalDb()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var queries []SavedQuery
+
+	err = db.View(func(tx *bolt.Tx) error {
+		saved := tx.Bucket([]byte(SAVED_QUERIES_BUCKET_NAME))
+		if saved == nil {
+			return nil
+		}
+
+		for _, scope := range []string{"", connName} {
+			b := saved.Bucket(scopeBucketName(scope))
+			if b == nil {
+				continue
+			}
+
+			err := b.ForEach(func(_, v []byte) error {
+				var q SavedQuery
+				if err := json.Unmarshal(v, &q); err != nil {
+					return nil
+				}
+				queries = append(queries, q)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	sort.SliceStable(queries, func(i, j int) bool {
+		if queries[i].Folder != queries[j].Folder {
+			return queries[i].Folder < queries[j].Folder
+		}
+		return queries[i].Name < queries[j].Name
+	})
+
+	return queries, err
+}
+
+// ExportSavedQueries writes the queries to a .sql file, each preceded by
+// "-- name:" and "-- scope:" header comments so the file can be imported again.
+func ExportSavedQueries(path string, queries []SavedQuery) error {
+	f, err := os.Create(expandHome(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, q := range queries {
+		scope := "global"
+		if q.Scope != "" {
+			scope = "connection"
+		}
+
+		fmt.Fprintf(w, "-- name: %s\n-- scope: %s\n%s\n\n", q.Path(), scope, strings.TrimSpace(q.Statement))
+	}
+
+	return w.Flush()
+}
+
+// ImportSavedQueries reads a file written by ExportSavedQueries. Queries with a
+// connection scope are imported into connName. A plain .sql file without
+// headers is imported as a single global query named after the file.
+func ImportSavedQueries(path string, connName string) (int, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	queries, err := parseSavedQueries(f, connName)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(queries) == 0 {
+		return 0, errors.New("no queries found in file")
+	}
+
+	if queries[0].Name == "" {
+		queries[0].Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	for _, q := range queries {
+		if err := SaveQuery(q); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(queries), nil
+}
+
+func parseSavedQueries(r io.Reader, connName string) ([]SavedQuery, error) {
+	var queries []SavedQuery
+	var statement strings.Builder
+	current := SavedQuery{}
+
+	flush := func() {
+		current.Statement = strings.TrimSpace(statement.String())
+		if current.Statement != "" {
+			queries = append(queries, current)
+		}
+		statement.Reset()
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if path, ok := strings.CutPrefix(trimmed, "-- name:"); ok {
+			flush()
+			current = SavedQuery{}
+			current.Folder, current.Name = parseSavedQueryPath(path)
+			continue
+		}
+
+		if scope, ok := strings.CutPrefix(trimmed, "-- scope:"); ok {
+			if strings.TrimSpace(scope) == "connection" {
+				current.Scope = connName
+			}
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteRune('\n')
+	}
+	flush()
+
+	return queries, scanner.Err()
+}
+
+func expandHome(path string) string {
+	path = strings.TrimSpace(path)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, rest)
+		}
+	}
+	return path
+}
+
+type savedQueryItem SavedQuery
+
+func (i savedQueryItem) FilterValue() string { return SavedQuery(i).Path() }
+
+type savedQueryItemDelegate struct{}
+
+func (d savedQueryItemDelegate) Height() int                             { return 1 }
+func (d savedQueryItemDelegate) Spacing() int                            { return 0 }
+func (d savedQueryItemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d savedQueryItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(savedQueryItem)
+	if !ok {
+		return
+	}
+
+	folder := ""
+	if i.Folder != "" {
+		folder = blurredStyle.Render(i.Folder + "/")
+	}
+
+	scope := blurredStyle.Render("[global]")
+	if i.Scope != "" {
+		scope = blurredStyle.Render("[" + i.Scope + "]")
+	}
+
+	str := fmt.Sprintf("%s%s %s", folder, i.Name, scope)
+	if index == m.Index() {
+		str = selectedItemStyle.Render("> ") + str
+	} else {
+		str = "  " + str
+	}
+
+	fmt.Fprint(w, str)
+}
+
+type SavedQueryPrompt string
+
+const (
+	NO_PROMPT     SavedQueryPrompt = ""
+	SAVE_PROMPT   SavedQueryPrompt = "SAVE"
+	EXPORT_PROMPT SavedQueryPrompt = "EXPORT"
+	IMPORT_PROMPT SavedQueryPrompt = "IMPORT"
+)
+
+type SavedQueriesModel struct {
+	list      list.Model
+	connName  string
+	prompt    textinput.Model
+	promptFor SavedQueryPrompt
+	statement string
+	global    bool
+	saveOnly  bool
+	selected  *SavedQuery
+	run       bool
+	back      bool
+	status    string
+	err       error
+}
+
+func NewSavedQueriesModel(connName string) SavedQueriesModel {
+	l := list.New(nil, savedQueryItemDelegate{}, width, cfg.PageSize)
+	l.Title = "Saved queries"
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+
+	prompt := textinput.New()
+	prompt.Cursor.Style = cursorStyle
+	prompt.PromptStyle = focusedItemStyle
+	prompt.TextStyle = focusedItemStyle
+
+	m := SavedQueriesModel{
+		list:     l,
+		connName: connName,
+		prompt:   prompt,
+	}
+	m.reload()
+
+	return m
+}
+
+// NewSaveQueryModel opens the saved queries prompting for the folder/name
+// to save statement under.
+func NewSaveQueryModel(connName string, statement string) (SavedQueriesModel, tea.Cmd) {
+	m := NewSavedQueriesModel(connName)
+	m.statement = statement
+	m.saveOnly = true
+
+	return m, m.openPrompt(SAVE_PROMPT, "folder/name")
+}
+
+func (m *SavedQueriesModel) reload() {
+	queries, err := ListSavedQueries(m.connName)
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	items := make([]list.Item, len(queries))
+	for i, q := range queries {
+		items[i] = savedQueryItem(q)
+	}
+	m.list.SetItems(items)
+}
+
+func (m *SavedQueriesModel) openPrompt(promptFor SavedQueryPrompt, placeholder string) tea.Cmd {
+	m.promptFor = promptFor
+	m.prompt.Reset()
+	m.prompt.Placeholder = placeholder
+	if promptFor != SAVE_PROMPT {
+		m.prompt.SetValue(defaultExportPath)
+	}
+
+	return m.prompt.Focus()
+}
+
+func (m *SavedQueriesModel) closePrompt() {
+	m.promptFor = NO_PROMPT
+	m.prompt.Blur()
+}
+
+func (m SavedQueriesModel) submitPrompt() SavedQueriesModel {
+	value := m.prompt.Value()
+
+	switch m.promptFor {
+	case SAVE_PROMPT:
+		q := SavedQuery{Statement: m.statement}
+		q.Folder, q.Name = parseSavedQueryPath(value)
+		if !m.global {
+			q.Scope = m.connName
+		}
+
+		if m.err = SaveQuery(q); m.err != nil {
+			return m
+		}
+		m.status = fmt.Sprintf("Saved %s", q.Path())
+		m.back = m.saveOnly
+
+	case EXPORT_PROMPT:
+		var queries []SavedQuery
+		for _, i := range m.list.VisibleItems() {
+			queries = append(queries, SavedQuery(i.(savedQueryItem)))
+		}
+
+		if m.err = ExportSavedQueries(value, queries); m.err != nil {
+			return m
+		}
+		m.status = fmt.Sprintf("Exported %d queries to %s", len(queries), value)
+
+	case IMPORT_PROMPT:
+		count, err := ImportSavedQueries(value, m.connName)
+		if m.err = err; err != nil {
+			return m
+		}
+		m.status = fmt.Sprintf("Imported %d queries from %s", count, value)
+		m.reload()
+	}
+
+	m.closePrompt()
+	return m
+}
+
+func (m SavedQueriesModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m SavedQueriesModel) Update(msg tea.Msg) (SavedQueriesModel, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.promptFor != NO_PROMPT {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch {
+			case key.Matches(msg, promptKeys.Cancel):
+				m.closePrompt()
+				m.err = nil
+				m.back = m.saveOnly
+				return m, nil
+
+			case key.Matches(msg, savedQueriesKeys.Scope):
+				if m.promptFor == SAVE_PROMPT {
+					m.global = !m.global
+				}
+				return m, nil
+
+			case key.Matches(msg, promptKeys.Submit):
+				return m.submitPrompt(), nil
+			}
+		}
+
+		m.prompt, cmd = m.prompt.Update(msg)
+		return m, cmd
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok && m.list.FilterState() != list.Filtering {
+		switch {
+		case key.Matches(msg, savedQueriesKeys.Quit):
+			m.back = true
+			return m, nil
+
+		case key.Matches(msg, savedQueriesKeys.Back):
+			if m.list.FilterState() == list.Unfiltered {
+				m.back = true
+				return m, nil
+			}
+
+		case key.Matches(msg, savedQueriesKeys.Run, savedQueriesKeys.Open):
+			if i, ok := m.list.SelectedItem().(savedQueryItem); ok {
+				q := SavedQuery(i)
+				m.selected = &q
+				m.run = key.Matches(msg, savedQueriesKeys.Run)
+			}
+			return m, nil
+
+		case key.Matches(msg, savedQueriesKeys.Delete):
+			if i, ok := m.list.SelectedItem().(savedQueryItem); ok {
+				if m.err = DeleteSavedQuery(SavedQuery(i)); m.err == nil {
+					m.status = fmt.Sprintf("Deleted %s", SavedQuery(i).Path())
+					m.reload()
+				}
+			}
+			return m, nil
+
+		case key.Matches(msg, savedQueriesKeys.Export):
+			return m, m.openPrompt(EXPORT_PROMPT, "export to .sql file")
+
+		case key.Matches(msg, savedQueriesKeys.Import):
+			return m, m.openPrompt(IMPORT_PROMPT, "import from .sql file")
+		}
+	}
+
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m SavedQueriesModel) View() string {
+	var b strings.Builder
+
+	switch m.promptFor {
+	case SAVE_PROMPT:
+		scope := m.connName
+		if m.global {
+			scope = "global"
+		}
+		fmt.Fprintf(&b, "Save query (%s)\n\n%s", selectedItemStyle.Render(scope), m.prompt.View())
+		b.WriteString(helpStyle.Render("\n\n" + keyHelp(withHelp(promptKeys.Submit, "save"), savedQueriesKeys.Scope, promptKeys.Cancel)))
+
+	case EXPORT_PROMPT, IMPORT_PROMPT:
+		title := "Export visible queries to"
+		if m.promptFor == IMPORT_PROMPT {
+			title = "Import queries from"
+		}
+		fmt.Fprintf(&b, "%s\n\n%s", title, m.prompt.View())
+		b.WriteString(helpStyle.Render("\n\n" + keyHelp(promptKeys.Submit, promptKeys.Cancel)))
+
+	default:
+		b.WriteString(m.list.View())
+		b.WriteString(helpStyle.Render("\n" + keyHelp(savedQueriesKeys.Run, savedQueriesKeys.Open, savedQueriesKeys.Delete,
+			savedQueriesKeys.Export, savedQueriesKeys.Import, withHelp(m.list.KeyMap.Filter, "search"), savedQueriesKeys.Back)))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n" + errorStyle.Render(m.err.Error()))
+	} else if m.status != "" {
+		b.WriteString("\n" + successStyle.Render(m.status))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type scriptStatement struct {
+	statement string
+	sql       string
+	names     []string
+}
+
+type StatementResult struct {
+	statement scriptStatement
+	table     Table
+	start     time.Time
+	duration  time.Duration
+	err       error
+	skipped   bool
+}
+
+// splitStatements splits a script on semicolons outside of strings, quoted
+// identifiers, dollar quoted bodies and comments. Statements made only of
+// whitespace and comments are dropped.
+func splitStatements(sql string) []string {
+	var statements []string
+	var b strings.Builder
+	empty := true
+
+	flush := func() {
+		if !empty {
+			statements = append(statements, strings.TrimSpace(b.String()))
+		}
+		b.Reset()
+		empty = true
+	}
+
+	for _, token := range tokenizeSQL(sql) {
+		if token.kind == TOKEN_OPERATOR && token.text == ";" {
+			flush()
+			continue
+		}
+
+		if token.kind != TOKEN_WHITESPACE && token.kind != TOKEN_COMMENT {
+			empty = false
+		}
+		b.WriteString(token.text)
+	}
+	flush()
+
+	return statements
+}
+
+// statementAt returns the statement of a script containing the rune offset,
+// or the last statement before it.
+func statementAt(sql string, offset int) string {
+	var current, last strings.Builder
+	position := 0
+	empty := true
+
+	for _, token := range tokenizeSQL(sql) {
+		if token.kind == TOKEN_OPERATOR && token.text == ";" {
+			if !empty {
+				last.Reset()
+				last.WriteString(current.String())
+			}
+			if position >= offset && last.Len() > 0 {
+				return strings.TrimSpace(last.String())
+			}
+			current.Reset()
+			empty = true
+			position++
+			continue
+		}
+
+		if token.kind != TOKEN_WHITESPACE && token.kind != TOKEN_COMMENT {
+			empty = false
+		}
+		current.WriteString(token.text)
+		position += len([]rune(token.text))
+	}
+
+	if !empty {
+		return strings.TrimSpace(current.String())
+	}
+
+	return strings.TrimSpace(last.String())
+}
+
+// parseScript binds the placeholders of every statement in a script in the
+// style of the connection and returns the union of their names.
+func parseScript(sql string, style PlaceholderStyle) ([]scriptStatement, []string, error) {
+	var statements []scriptStatement
+	var names []string
+	seen := map[string]bool{}
+
+	for i, statement := range splitStatements(sql) {
+		bound, statementNames, err := bindParameters(statement, style)
+		if err != nil {
+			return nil, nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+
+		for _, name := range statementNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+
+		statements = append(statements, scriptStatement{
+			statement: statement,
+			sql:       bound,
+			names:     statementNames,
+		})
+	}
+
+	return statements, names, nil
+}
+
+// args returns the values to bind for the statement. Strings are sent in the
+// text format so the server casts them to the parameter types.
+func (s scriptStatement) args(values map[string]*string) []any {
+	args := make([]any, len(s.names))
+	for i, name := range s.names {
+		if value := values[name]; value != nil {
+			args[i] = *value
+		}
+	}
+
+	return args
+}
+
+// RunScript runs the statements in order on the session so session state
+// carries over between them. With stopOnError the statements after a failure
+// are skipped.
+func (s *Session) RunScript(statements []scriptStatement, values map[string]*string, stopOnError bool) []StatementResult {
+	results := make([]StatementResult, len(statements))
+	for i, statement := range statements {
+		results[i] = StatementResult{statement: statement, skipped: true}
+	}
+
+	for i, statement := range statements {
+		start := time.Now()
+
+		table, err := s.Query(statement.sql, statement.args(values)...)
+		results[i].table = table
+
+		results[i].start = start
+		results[i].duration = time.Since(start)
+		results[i].err = err
+		results[i].skipped = false
+
+		if err != nil && stopOnError {
+			break
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{sql: "", want: nil},
+		{sql: " ;; -- only a comment\n;", want: nil},
+		{sql: "select 1; select 2", want: []string{"select 1", "select 2"}},
+		{sql: "select 1;\n\nselect 2;\n", want: []string{"select 1", "select 2"}},
+		{sql: "select ';'; select \";\"", want: []string{"select ';'", "select \";\""}},
+		{sql: "select E'\\';'; select 2", want: []string{"select E'\\';'", "select 2"}},
+		{
+			sql: "create function f() returns int as $$ select 1; $$ language sql; select f()",
+			want: []string{
+				"create function f() returns int as $$ select 1; $$ language sql",
+				"select f()",
+			},
+		},
+		{sql: "select 1 /* ; /* ; */ ; */; select 2", want: []string{"select 1 /* ; /* ; */ ; */", "select 2"}},
+		{sql: "select 1 -- ;\n; select 2", want: []string{"select 1 -- ;", "select 2"}},
+	}
+
+	for _, test := range tests {
+		if got := splitStatements(test.sql); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitStatements(%q)\n got %q\nwant %q", test.sql, got, test.want)
+		}
+	}
+}
+
+func TestStatementAt(t *testing.T) {
+	sql := "select 1; select 2;\n\n-- done\n"
+
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{offset: 0, want: "select 1"},
+		{offset: 8, want: "select 1"},
+		{offset: 12, want: "select 2"},
+		{offset: len(sql), want: "select 2"},
+	}
+
+	for _, test := range tests {
+		if got := statementAt(sql, test.offset); got != test.want {
+			t.Errorf("statementAt(%d) = %q, want %q", test.offset, got, test.want)
+		}
+	}
+}
+
+func TestParseScript(t *testing.T) {
+	statements, names, err := parseScript("select :a; select :b, :a", PLACEHOLDER_DOLLAR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("names = %v", names)
+	}
+	if len(statements) != 2 || statements[1].sql != "select $1, $2" ||
+		!reflect.DeepEqual(statements[1].names, []string{"b", "a"}) {
+		t.Errorf("statements = %+v", statements)
+	}
+
+	if _, _, err := parseScript("select 1; select :a, $1", PLACEHOLDER_DOLLAR); err == nil || err.Error()[:11] != "statement 2" {
+		t.Errorf("err = %v, want it to name statement 2", err)
+	}
+
+	statements, names, err = parseScript("select :b, :a, :b", PLACEHOLDER_QUESTION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"b", "a"}) || statements[0].sql != "select ?, ?, ?" {
+		t.Errorf("? style: names = %v, statements = %+v", names, statements)
+	}
+
+	value := "x"
+	args := statements[0].args(map[string]*string{"a": &value})
+	if args[0] != nil || args[1] != "x" || args[2] != nil {
+		t.Errorf("args = %v", args)
+	}
+}
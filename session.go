@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TxStatus byte
+
+const (
+	TX_IDLE   TxStatus = 'I'
+	TX_ACTIVE TxStatus = 'T'
+	TX_FAILED TxStatus = 'E'
+)
+
+// Session is a connection kept open for the lifetime of the database view so
+// transactions and session settings carry over between statements.
+type Session struct {
+	conn DriverConn
+}
+
+func (params Connection) OpenSession() (*Session, error) {
+	driver, err := params.driver()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := driver.Connect(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{conn: conn}, nil
+}
+
+func (s *Session) Close() {
+	if s != nil && s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+func (s *Session) Closed() bool {
+	return s == nil || s.conn == nil || s.conn.Closed()
+}
+
+// TxStatus reports the transaction state of the session as last seen by the
+// server, including transactions begun by statements typed in the editor.
+func (s *Session) TxStatus() TxStatus {
+	if s.Closed() {
+		return TX_IDLE
+	}
+
+	return s.conn.TxStatus()
+}
+
+// Cancel interrupts the statement running on the session, if any.
+func (s *Session) Cancel() error {
+	if s.Closed() {
+		return nil
+	}
+
+	return s.conn.Cancel()
+}
+
+func (s *Session) Exec(sql string) error {
+	return s.conn.Exec(context.Background(), sql)
+}
+
+func (s *Session) Begin() error {
+	return s.Exec("BEGIN")
+}
+
+func (s *Session) Commit() error {
+	return s.Exec("COMMIT")
+}
+
+func (s *Session) Rollback() error {
+	return s.Exec("ROLLBACK")
+}
+
+func (s *Session) Savepoint(name string) error {
+	return s.Exec(fmt.Sprintf("SAVEPOINT %s", name))
+}
+
+func (s *Session) RollbackTo(name string) error {
+	return s.Exec(fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", name))
+}
+
+func (s *Session) SelectAll(table string) (Table, error) {
+	return s.Query(fmt.Sprintf("SELECT * FROM %s", table))
+}
+
+func (s *Session) Query(sql string, args ...any) (Table, error) {
+	return s.conn.Query(context.Background(), sql, args...)
+}
+
+func (s *Session) QueryText(sql string) (ResultSet, error) {
+	return s.conn.QueryText(context.Background(), sql)
+}
+
+// postgres returns the underlying connection for the features only postgres
+// has.
+func (s *Session) postgres() (*pgx.Conn, error) {
+	conn, ok := s.conn.(*postgresConn)
+	if !ok {
+		return nil, errors.New("only available for postgres connections")
+	}
+
+	return conn.conn, nil
+}
@@ -0,0 +1,676 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/scrypt"
+)
+
+const connectionsFileVersion = 1
+
+// Cost parameters of the key derivation, stored in the file so they can be
+// raised later without breaking older exports
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Limits on the cost parameters read from a file. They leave room to raise
+// the exported ones but keep a crafted file from taking gigabytes of memory,
+// which scrypt needs 128·N·r bytes of, or minutes to open.
+const (
+	maxScryptMemory = 256 << 20
+	maxScryptRP     = 64
+)
+
+type secretsKey struct {
+	KDF  string `json:"kdf"`
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+type sealedSecret struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type exportedConnection struct {
+	Connection
+	User     string        `json:"user,omitempty"`
+	Password *sealedSecret `json:"password,omitempty"`
+}
+
+// connectionsFile is the shareable form of saved connections. Passwords are
+// only present when exported with a passphrase, sealed with AES-GCM under a
+// key derived from it with scrypt.
+type connectionsFile struct {
+	Version     int                  `json:"version"`
+	Encryption  *secretsKey          `json:"encryption,omitempty"`
+	Connections []exportedConnection `json:"connections"`
+}
+
+func (k secretsKey) aead(passphrase string) (cipher.AEAD, error) {
+	if k.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation %q", k.KDF)
+	}
+	if k.R < 1 || k.P < 1 || k.R > maxScryptRP/k.P || k.N < 2 || k.N > maxScryptMemory/(128*k.R) {
+		return nil, fmt.Errorf("unsupported scrypt parameters N=%d r=%d p=%d", k.N, k.R, k.P)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), k.Salt, k.N, k.R, k.P, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// ExportConnections writes the connections to a JSON file. Without a
+// passphrase the passwords are left out.
+func ExportConnections(path string, conns []Connection, passphrase string) error {
+	file := connectionsFile{Version: connectionsFileVersion}
+
+	var aead cipher.AEAD
+	if passphrase != "" {
+		key := secretsKey{KDF: "scrypt", Salt: make([]byte, 16), N: scryptN, R: scryptR, P: scryptP}
+		if _, err := rand.Read(key.Salt); err != nil {
+			return err
+		}
+
+		var err error
+		if aead, err = key.aead(passphrase); err != nil {
+			return err
+		}
+		file.Encryption = &key
+	}
+
+	for _, conn := range conns {
+		user, pass, err := credentials(conn.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", conn.Name, err)
+		}
+
+		exported := exportedConnection{Connection: conn, User: user}
+		exported.LastUsed = conn.LastUsed.UTC()
+
+		if aead != nil && pass != "" && !conn.PgpassLinked {
+			nonce := make([]byte, aead.NonceSize())
+			if _, err := rand.Read(nonce); err != nil {
+				return err
+			}
+
+			// The name is authenticated so a password cannot be moved to another entry
+			exported.Password = &sealedSecret{
+				Nonce:      nonce,
+				Ciphertext: aead.Seal(nil, nonce, []byte(pass), []byte(conn.Name)),
+			}
+		}
+
+		file.Connections = append(file.Connections, exported)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(expandHome(path), append(data, '\n'), 0600)
+}
+
+// ReadConnectionsFile reads exported connections. Sealed passwords are
+// opened with the passphrase, or skipped without one and counted in the
+// number returned.
+func ReadConnectionsFile(path string, passphrase string) ([]Connection, int, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var file connectionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, 0, fmt.Errorf("not a connections file: %w", err)
+	}
+
+	if file.Version != connectionsFileVersion {
+		return nil, 0, fmt.Errorf("unsupported connections file version %d", file.Version)
+	}
+
+	var aead cipher.AEAD
+	if file.Encryption != nil && passphrase != "" {
+		if aead, err = file.Encryption.aead(passphrase); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	conns := make([]Connection, len(file.Connections))
+	skipped := 0
+	for i, exported := range file.Connections {
+		conn := exported.Connection
+		conn.User = exported.User
+
+		switch {
+		case exported.Password == nil:
+		case aead == nil:
+			skipped++
+		default:
+			pass, err := aead.Open(nil, exported.Password.Nonce, exported.Password.Ciphertext, []byte(conn.Name))
+			if err != nil {
+				return nil, 0, errors.New("wrong passphrase")
+			}
+			conn.Pass = string(pass)
+		}
+
+		conns[i] = conn
+	}
+
+	return conns, skipped, nil
+}
+
+type ConflictResolution string
+
+const (
+	SKIP      ConflictResolution = "skip"
+	OVERWRITE ConflictResolution = "overwrite"
+	RENAME    ConflictResolution = "rename"
+)
+
+// uniqueName appends a number to the name until no saved connection uses it.
+func uniqueName(name string, saved map[string]bool) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		if !saved[candidate] {
+			return candidate
+		}
+	}
+}
+
+type ShareMode string
+
+const (
+	EXPORT_CONNECTIONS ShareMode = "EXPORT"
+	IMPORT_CONNECTIONS ShareMode = "IMPORT"
+)
+
+type ShareStep string
+
+const (
+	SHARE_SELECT ShareStep = "SELECT"
+	SHARE_FILE   ShareStep = "FILE"
+	SHARE_DONE   ShareStep = "DONE"
+)
+
+type shareItem struct {
+	conn       Connection
+	selected   bool
+	conflict   bool
+	resolution ConflictResolution
+}
+
+func (i shareItem) FilterValue() string { return i.conn.Name }
+
+type shareItemDelegate struct{}
+
+func (d shareItemDelegate) Height() int                             { return 1 }
+func (d shareItemDelegate) Spacing() int                            { return 0 }
+func (d shareItemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d shareItemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(shareItem)
+	if !ok {
+		return
+	}
+
+	check := "[ ]"
+	if i.selected {
+		check = "[x]"
+	}
+
+	str := fmt.Sprintf("%s %s", check, i.conn.Name)
+	if index == m.Index() {
+		str = selectedItemStyle.Render("> " + str)
+	} else {
+		str = itemStyle.Render(str)
+	}
+
+	details := fmt.Sprintf("%s:%s/%s", i.conn.Host, i.conn.Port, i.conn.Database)
+	if i.conflict {
+		details += ", name already saved: " + string(i.resolution)
+	}
+
+	fmt.Fprint(w, str+"  "+blurredStyle.Render(details))
+}
+
+// ShareConnectionsModel exports saved connections to a file, or imports them
+// from one resolving names that are already saved.
+type ShareConnectionsModel struct {
+	mode       ShareMode
+	step       ShareStep
+	list       list.Model
+	inputs     []textinput.Model
+	focusIndex int
+	secrets    bool
+	sealed     int
+	status     string
+	err        error
+	back       bool
+}
+
+func newShareInputs() []textinput.Model {
+	path := textinput.New()
+	path.Cursor.Style = cursorStyle
+	path.Prompt = "File: "
+	path.Placeholder = "~/termtable-connections.json"
+
+	passphrase := textinput.New()
+	passphrase.Cursor.Style = cursorStyle
+	passphrase.Prompt = "Passphrase: "
+	passphrase.Placeholder = "leave empty to skip passwords"
+	passphrase.EchoMode = textinput.EchoPassword
+	passphrase.EchoCharacter = '•'
+
+	return []textinput.Model{path, passphrase}
+}
+
+func newShareList(title string) list.Model {
+	l := list.New(nil, shareItemDelegate{}, width, cfg.PageSize)
+	l.Title = title
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+
+	return l
+}
+
+func NewExportConnectionsModel() ShareConnectionsModel {
+	m := ShareConnectionsModel{
+		mode:   EXPORT_CONNECTIONS,
+		step:   SHARE_SELECT,
+		list:   newShareList("Export connections"),
+		inputs: newShareInputs(),
+	}
+
+	conns, err := ListConnections()
+	m.err = err
+
+	items := make([]list.Item, len(conns))
+	for i, conn := range conns {
+		items[i] = shareItem{conn: conn, selected: true}
+	}
+	m.list.SetItems(items)
+
+	return m
+}
+
+func NewImportConnectionsModel() (ShareConnectionsModel, tea.Cmd) {
+	m := ShareConnectionsModel{
+		mode:   IMPORT_CONNECTIONS,
+		step:   SHARE_FILE,
+		list:   newShareList("Import connections"),
+		inputs: newShareInputs(),
+	}
+
+	return m, m.updateFocus()
+}
+
+func (m *ShareConnectionsModel) updateFocus() tea.Cmd {
+	var cmd tea.Cmd
+	for i := range m.inputs {
+		if i == m.focusIndex {
+			cmd = m.inputs[i].Focus()
+			m.inputs[i].PromptStyle = focusedItemStyle
+			m.inputs[i].TextStyle = focusedItemStyle
+			continue
+		}
+		m.inputs[i].Blur()
+		m.inputs[i].PromptStyle = noStyle
+		m.inputs[i].TextStyle = noStyle
+	}
+
+	return cmd
+}
+
+// visibleInputs leaves out the passphrase when exporting without secrets.
+func (m ShareConnectionsModel) visibleInputs() int {
+	if m.mode == EXPORT_CONNECTIONS && !m.secrets {
+		return 1
+	}
+
+	return len(m.inputs)
+}
+
+func (m ShareConnectionsModel) selected() []shareItem {
+	var items []shareItem
+	for _, listItem := range m.list.Items() {
+		if item := listItem.(shareItem); item.selected {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func (m ShareConnectionsModel) export() ShareConnectionsModel {
+	var conns []Connection
+	for _, item := range m.selected() {
+		conns = append(conns, item.conn)
+	}
+
+	passphrase := ""
+	if m.secrets {
+		passphrase = m.inputs[1].Value()
+		if passphrase == "" {
+			m.err = errors.New("a passphrase is needed to export passwords")
+			return m
+		}
+	}
+
+	path := m.inputs[0].Value()
+	if m.err = ExportConnections(path, conns, passphrase); m.err != nil {
+		return m
+	}
+
+	m.step = SHARE_DONE
+	m.status = fmt.Sprintf("Exported %d connections to %s", len(conns), path)
+	if !m.secrets {
+		m.status += " without passwords"
+	}
+
+	return m
+}
+
+// preview reads the file and lists its connections, flagging the names that
+// are already saved.
+func (m ShareConnectionsModel) preview() ShareConnectionsModel {
+	conns, skipped, err := ReadConnectionsFile(m.inputs[0].Value(), m.inputs[1].Value())
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	m.sealed = skipped
+
+	saved := map[string]bool{}
+	existing, err := ListConnections()
+	if err != nil {
+		m.err = err
+		return m
+	}
+	for _, conn := range existing {
+		saved[conn.Name] = true
+	}
+
+	items := make([]list.Item, len(conns))
+	for i, conn := range conns {
+		item := shareItem{conn: conn, selected: true, conflict: saved[conn.Name]}
+		if item.conflict {
+			item.resolution = SKIP
+		}
+		items[i] = item
+	}
+	m.list.SetItems(items)
+
+	m.step = SHARE_SELECT
+	m.err = nil
+	return m
+}
+
+func (m ShareConnectionsModel) save() ShareConnectionsModel {
+	saved := map[string]bool{}
+	existing, err := ListConnections()
+	if err != nil {
+		m.err = err
+		return m
+	}
+	for _, conn := range existing {
+		saved[conn.Name] = true
+	}
+
+	imported, skipped := 0, 0
+	for _, item := range m.selected() {
+		conn := item.conn
+
+		if saved[conn.Name] {
+			switch item.resolution {
+			case SKIP:
+				skipped++
+				continue
+			case RENAME:
+				conn.Name = uniqueName(conn.Name, saved)
+			case OVERWRITE:
+				// Keep the saved password when the file has none
+				if _, pass, err := credentials(conn.Name); err == nil && conn.Pass == "" {
+					conn.Pass = pass
+				}
+			}
+		}
+
+		if err := SaveConnection(conn); err != nil {
+			m.err = fmt.Errorf("%s: %w", conn.Name, err)
+			return m
+		}
+		saved[conn.Name] = true
+		imported++
+	}
+
+	status := fmt.Sprintf("Imported %d connections", imported)
+	if skipped > 0 {
+		status += fmt.Sprintf(", skipped %d already saved", skipped)
+	}
+	if m.sealed > 0 {
+		status += fmt.Sprintf(", %d passwords left out without the passphrase", m.sealed)
+	}
+
+	m.step = SHARE_DONE
+	m.status = status
+
+	return m
+}
+
+func (m ShareConnectionsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ShareConnectionsModel) Update(msg tea.Msg) (ShareConnectionsModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		// Cursor blinks and list messages go to what is on screen
+		var cmd tea.Cmd
+		switch m.step {
+		case SHARE_DONE:
+		case SHARE_FILE:
+			m.inputs[m.focusIndex], cmd = m.inputs[m.focusIndex].Update(msg)
+		default:
+			m.list, cmd = m.list.Update(msg)
+		}
+		return m, cmd
+	}
+
+	if key.Matches(keyMsg, shareKeys.Quit) {
+		m.back = true
+		return m, nil
+	}
+
+	switch m.step {
+	case SHARE_DONE:
+		m.back = true
+		return m, nil
+
+	case SHARE_FILE:
+		switch {
+		case key.Matches(keyMsg, promptKeys.Cancel):
+			if m.mode == EXPORT_CONNECTIONS {
+				m.step = SHARE_SELECT
+				m.err = nil
+			} else {
+				m.back = true
+			}
+			return m, nil
+
+		case key.Matches(keyMsg, promptKeys.NextField):
+			m.focusIndex = (m.focusIndex + 1) % m.visibleInputs()
+			return m, m.updateFocus()
+
+		case key.Matches(keyMsg, promptKeys.PrevField):
+			m.focusIndex = (m.focusIndex + m.visibleInputs() - 1) % m.visibleInputs()
+			return m, m.updateFocus()
+
+		case key.Matches(keyMsg, promptKeys.Submit):
+			if m.inputs[0].Value() == "" {
+				m.err = errors.New("enter the path of the file")
+				return m, nil
+			}
+			if m.mode == EXPORT_CONNECTIONS {
+				return m.export(), nil
+			}
+			return m.preview(), nil
+		}
+
+		var cmd tea.Cmd
+		m.inputs[m.focusIndex], cmd = m.inputs[m.focusIndex].Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(keyMsg, shareKeys.Back):
+		if m.mode == IMPORT_CONNECTIONS {
+			m.step = SHARE_FILE
+			return m, m.updateFocus()
+		}
+		m.back = true
+		return m, nil
+
+	case key.Matches(keyMsg, shareKeys.Toggle):
+		if item, ok := m.list.SelectedItem().(shareItem); ok {
+			item.selected = !item.selected
+			m.list.SetItem(m.list.Index(), item)
+		}
+		return m, nil
+
+	case key.Matches(keyMsg, shareKeys.SelectAll):
+		all := len(m.selected()) < len(m.list.Items())
+		for i, listItem := range m.list.Items() {
+			item := listItem.(shareItem)
+			item.selected = all
+			m.list.SetItem(i, item)
+		}
+		return m, nil
+
+	// Include the passwords, encrypted with a passphrase
+	case key.Matches(keyMsg, shareKeys.Passwords):
+		if m.mode == EXPORT_CONNECTIONS {
+			m.secrets = !m.secrets
+		}
+		return m, nil
+
+	// Cycle what to do with a name that is already saved
+	case key.Matches(keyMsg, shareKeys.Resolve):
+		if item, ok := m.list.SelectedItem().(shareItem); ok && item.conflict {
+			switch item.resolution {
+			case SKIP:
+				item.resolution = OVERWRITE
+			case OVERWRITE:
+				item.resolution = RENAME
+			default:
+				item.resolution = SKIP
+			}
+			m.list.SetItem(m.list.Index(), item)
+		}
+		return m, nil
+
+	case key.Matches(keyMsg, shareKeys.Next):
+		if len(m.selected()) == 0 {
+			m.err = errors.New("no connections selected")
+			return m, nil
+		}
+		m.err = nil
+
+		if m.mode == IMPORT_CONNECTIONS {
+			return m.save(), nil
+		}
+
+		m.step = SHARE_FILE
+		m.focusIndex = 0
+		return m, m.updateFocus()
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m ShareConnectionsModel) View() string {
+	var b strings.Builder
+
+	switch m.step {
+	case SHARE_DONE:
+		b.WriteString(successStyle.Render(m.status))
+		b.WriteString(helpStyle.Render("\n\npress any key to go back"))
+		return b.String()
+
+	case SHARE_FILE:
+		if m.mode == EXPORT_CONNECTIONS {
+			b.WriteString("Export connections\n\n")
+		} else {
+			b.WriteString("Import connections\n\n")
+		}
+
+		for i := 0; i < m.visibleInputs(); i++ {
+			b.WriteString(m.inputs[i].View() + "\n")
+		}
+
+		if m.err != nil {
+			b.WriteString("\n" + errorStyle.Render(m.err.Error()))
+		}
+		b.WriteString(helpStyle.Render("\n" + keyHelp(withHelp(promptKeys.Submit, "continue"), promptKeys.NextField,
+			withHelp(promptKeys.Cancel, "back"))))
+		return b.String()
+	}
+
+	b.WriteString(m.list.View())
+
+	if m.mode == EXPORT_CONNECTIONS {
+		secrets := "[ ]"
+		if m.secrets {
+			secrets = "[x]"
+		}
+		fmt.Fprintf(&b, "\n%s Include passwords, encrypted with a passphrase (%s)", secrets, shareKeys.Passwords.Help().Key)
+	} else if m.sealed > 0 {
+		b.WriteString("\n" + errorStyle.Render(fmt.Sprintf(
+			"%d passwords are encrypted and will be skipped, go back to enter the passphrase", m.sealed)))
+	}
+
+	if m.err != nil {
+		b.WriteString("\n" + errorStyle.Render(m.err.Error()))
+	}
+
+	if m.mode == EXPORT_CONNECTIONS {
+		b.WriteString(helpStyle.Render("\n" + keyHelp(shareKeys.Toggle, shareKeys.SelectAll, shareKeys.Passwords, shareKeys.Next,
+			shareKeys.Back)))
+	} else {
+		b.WriteString(helpStyle.Render("\n" + keyHelp(shareKeys.Toggle, shareKeys.SelectAll, shareKeys.Resolve,
+			withHelp(shareKeys.Next, "import"), shareKeys.Back)))
+	}
+
+	return b.String()
+}
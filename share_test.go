@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// exportTestConnections saves two connections to the mock keyring and exports
+// them, returning the path of the file.
+func exportTestConnections(t *testing.T, passphrase string) (string, []Connection) {
+	t.Helper()
+	keyring.MockInit()
+
+	conns := []Connection{
+		{Name: "prod", Host: "db.example.com", Port: "5432", Database: "app"},
+		{Name: "local", Host: "localhost", Port: "5432", Database: "dev"},
+	}
+	for _, conn := range conns {
+		if err := keyring.Set(SERVICE, conn.Name, "alice:secret-"+conn.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "connections.json")
+	if err := ExportConnections(path, conns, passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, conns
+}
+
+// editConnectionsFile rewrites the file after changing its decoded form.
+func editConnectionsFile(t *testing.T, path string, edit func(*connectionsFile)) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var file connectionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	edit(&file)
+
+	if data, err = json.Marshal(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnectionsFileRoundTrip(t *testing.T) {
+	path, conns := exportTestConnections(t, "correct horse")
+
+	got, skipped, err := ReadConnectionsFile(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 0 {
+		t.Errorf("skipped = %d with the passphrase", skipped)
+	}
+
+	for i := range conns {
+		conns[i].User = "alice"
+		conns[i].Pass = "secret-" + conns[i].Name
+	}
+	if !reflect.DeepEqual(got, conns) {
+		t.Errorf("ReadConnectionsFile()\n got %+v\nwant %+v", got, conns)
+	}
+}
+
+func TestConnectionsFileSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		edit       func(*connectionsFile)
+		skipped    int
+		err        string
+	}{
+		{name: "missing passphrase", skipped: 2},
+		{name: "wrong passphrase", passphrase: "battery staple", err: "wrong passphrase"},
+		{
+			name:       "moved password",
+			passphrase: "correct horse",
+			edit:       func(file *connectionsFile) { file.Connections[0].Name = "staging" },
+			err:        "wrong passphrase",
+		},
+		{
+			name:       "swapped passwords",
+			passphrase: "correct horse",
+			edit: func(file *connectionsFile) {
+				file.Connections[0].Password, file.Connections[1].Password = file.Connections[1].Password, file.Connections[0].Password
+			},
+			err: "wrong passphrase",
+		},
+		{
+			name:       "costly key derivation",
+			passphrase: "correct horse",
+			edit:       func(file *connectionsFile) { file.Encryption.N = 1 << 30 },
+			err:        "unsupported scrypt parameters",
+		},
+		{
+			name:       "costly parallelism",
+			passphrase: "correct horse",
+			edit:       func(file *connectionsFile) { file.Encryption.P = 1 << 20 },
+			err:        "unsupported scrypt parameters",
+		},
+		{
+			name:       "no cost",
+			passphrase: "correct horse",
+			edit:       func(file *connectionsFile) { file.Encryption.R = 0 },
+			err:        "unsupported scrypt parameters",
+		},
+	}
+
+	for _, test := range tests {
+		path, _ := exportTestConnections(t, "correct horse")
+		if test.edit != nil {
+			editConnectionsFile(t, path, test.edit)
+		}
+
+		conns, skipped, err := ReadConnectionsFile(path, test.passphrase)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: err = %v, want %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if skipped != test.skipped {
+			t.Errorf("%s: skipped = %d, want %d", test.name, skipped, test.skipped)
+		}
+		for _, conn := range conns {
+			if conn.Pass != "" {
+				t.Errorf("%s: %s has password %q", test.name, conn.Name, conn.Pass)
+			}
+		}
+	}
+}
+
+func TestConnectionsFileWithoutPasswords(t *testing.T) {
+	path, _ := exportTestConnections(t, "")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("passwords exported without a passphrase:\n%s", data)
+	}
+
+	conns, skipped, err := ReadConnectionsFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 0 || len(conns) != 2 || conns[0].User != "alice" {
+		t.Errorf("conns = %+v, skipped = %d", conns, skipped)
+	}
+}
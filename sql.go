@@ -0,0 +1,269 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenKind string
+
+const (
+	TOKEN_WHITESPACE   TokenKind = "WHITESPACE"
+	TOKEN_COMMENT      TokenKind = "COMMENT"
+	TOKEN_STRING       TokenKind = "STRING"
+	TOKEN_QUOTED_IDENT TokenKind = "QUOTED_IDENT"
+	TOKEN_WORD         TokenKind = "WORD"
+	TOKEN_NUMBER       TokenKind = "NUMBER"
+	TOKEN_PARAM        TokenKind = "PARAM"
+	TOKEN_OPERATOR     TokenKind = "OPERATOR"
+)
+
+type sqlToken struct {
+	kind TokenKind
+	text string
+}
+
+const sqlOperatorChars = "+-*/<>=~!@#%^&|?"
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentChar(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// tokenizeSQL splits a statement into tokens following the Postgres lexical
+// rules closely enough to find strings, comments, dollar quoted bodies and
+// placeholders, plus the backtick identifiers and ? placeholders of MySQL.
+// Concatenating the text of all tokens returns the input.
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+
+	for i := 0; i < len(sql); {
+		kind, n := nextSQLToken(sql[i:], tokens)
+		tokens = append(tokens, sqlToken{kind: kind, text: sql[i : i+n]})
+		i += n
+	}
+
+	return tokens
+}
+
+func nextSQLToken(s string, previous []sqlToken) (TokenKind, int) {
+	r, size := utf8.DecodeRuneInString(s)
+
+	switch {
+	case unicode.IsSpace(r):
+		return TOKEN_WHITESPACE, len(s) - len(strings.TrimLeftFunc(s, unicode.IsSpace))
+
+	case strings.HasPrefix(s, "--"):
+		if end := strings.IndexByte(s, '\n'); end >= 0 {
+			return TOKEN_COMMENT, end
+		}
+		return TOKEN_COMMENT, len(s)
+
+	case strings.HasPrefix(s, "/*"):
+		return TOKEN_COMMENT, blockCommentLength(s)
+
+	case r == '\'':
+		return TOKEN_STRING, quotedLength(s, '\'', false)
+
+	case (r == 'E' || r == 'e') && strings.HasPrefix(s[1:], "'"):
+		return TOKEN_STRING, 1 + quotedLength(s[1:], '\'', true)
+
+	case r == '"':
+		return TOKEN_QUOTED_IDENT, quotedLength(s, '"', false)
+
+	case r == '`':
+		return TOKEN_QUOTED_IDENT, quotedLength(s, '`', false)
+
+	// A ? on its own, as opposed to the jsonb operators ?| and ?&
+	case r == '?' && !operatorFollows(s[1:]):
+		return TOKEN_PARAM, 1
+
+	case r == '$':
+		if n := digitsLength(s[1:]); n > 0 {
+			return TOKEN_PARAM, 1 + n
+		}
+		if n := dollarQuotedLength(s); n > 0 {
+			return TOKEN_STRING, n
+		}
+		return TOKEN_OPERATOR, 1
+
+	case r == ':':
+		if strings.HasPrefix(s, "::") {
+			return TOKEN_OPERATOR, 2
+		}
+		// A colon directly after an identifier or closing bracket is array
+		// slice syntax, not a named placeholder
+		next, _ := utf8.DecodeRuneInString(s[1:])
+		if isIdentStart(next) && !followsOperand(previous) {
+			return TOKEN_PARAM, 1 + identLength(s[1:])
+		}
+		return TOKEN_OPERATOR, 1
+
+	case unicode.IsDigit(r) || (r == '.' && len(s) > 1 && s[1] >= '0' && s[1] <= '9'):
+		return TOKEN_NUMBER, numberLength(s)
+
+	case isIdentStart(r):
+		return TOKEN_WORD, identLength(s)
+
+	case strings.ContainsRune(sqlOperatorChars, r):
+		n := 0
+		for n < len(s) && strings.IndexByte(sqlOperatorChars, s[n]) >= 0 {
+			// Stop before a comment starting inside an operator
+			if strings.HasPrefix(s[n:], "--") || strings.HasPrefix(s[n:], "/*") {
+				break
+			}
+			// and before a placeholder compared against, as in id=?
+			if n > 0 && s[n] == '?' && strings.IndexByte("=<>", s[n-1]) >= 0 && !operatorFollows(s[n+1:]) {
+				break
+			}
+			n++
+		}
+		return TOKEN_OPERATOR, max(n, 1)
+	}
+
+	return TOKEN_OPERATOR, size
+}
+
+// operatorFollows reports whether s starts with an operator character.
+func operatorFollows(s string) bool {
+	return s != "" && strings.IndexByte(sqlOperatorChars, s[0]) >= 0
+}
+
+func followsOperand(previous []sqlToken) bool {
+	if len(previous) == 0 {
+		return false
+	}
+
+	last := previous[len(previous)-1]
+	switch last.kind {
+	case TOKEN_WORD, TOKEN_QUOTED_IDENT, TOKEN_NUMBER:
+		return !isSQLKeyword(last.text)
+	case TOKEN_OPERATOR:
+		return last.text == ")" || last.text == "]"
+	}
+
+	return false
+}
+
+func blockCommentLength(s string) int {
+	depth := 0
+	for i := 0; i < len(s)-1; i++ {
+		switch {
+		case s[i] == '/' && s[i+1] == '*':
+			depth++
+			i++
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// quotedLength returns the length of a quoted string or identifier where the
+// quote is escaped by doubling it, or optionally with a backslash.
+func quotedLength(s string, quote byte, backslash bool) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case backslash && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(s)
+}
+
+// dollarQuotedLength returns the length of a $tag$...$tag$ string, or 0 if s
+// does not start with a dollar quote tag.
+func dollarQuotedLength(s string) int {
+	end := strings.IndexByte(s[1:], '$')
+	if end < 0 {
+		return 0
+	}
+
+	tag := s[:end+2]
+	for i, r := range tag[1 : len(tag)-1] {
+		if !(isIdentStart(r) || (i > 0 && unicode.IsDigit(r))) {
+			return 0
+		}
+	}
+
+	if closing := strings.Index(s[len(tag):], tag); closing >= 0 {
+		return len(tag) + closing + len(tag)
+	}
+
+	return len(s)
+}
+
+func identLength(s string) int {
+	for i, r := range s {
+		if !isIdentChar(r) {
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+func digitsLength(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+
+	return n
+}
+
+func numberLength(s string) int {
+	n := digitsLength(s)
+	if n < len(s) && s[n] == '.' {
+		n += 1 + digitsLength(s[n+1:])
+	}
+
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		exponent := n + 1
+		if exponent < len(s) && (s[exponent] == '+' || s[exponent] == '-') {
+			exponent++
+		}
+		if digits := digitsLength(s[exponent:]); digits > 0 {
+			n = exponent + digits
+		}
+	}
+
+	return n
+}
+
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`
+		ALL ALTER ANALYZE AND ANY ARRAY AS ASC BEGIN BETWEEN BY CASE CAST CHECK
+		COLLATE COLUMN COMMIT CONSTRAINT COPY CREATE CROSS CURRENT_DATE
+		CURRENT_TIME CURRENT_TIMESTAMP DEFAULT DELETE DESC DISTINCT DO DROP ELSE
+		END EXCEPT EXISTS EXPLAIN FALSE FETCH FILTER FOR FOREIGN FROM FULL
+		FUNCTION GRANT GROUP HAVING ILIKE IN INDEX INNER INSERT INTERSECT INTO IS
+		JOIN KEY LATERAL LEFT LIKE LIMIT NOT NULL OFFSET ON OR ORDER OUTER OVER
+		PARTITION PRIMARY REFERENCES RETURNING REVOKE RIGHT ROLLBACK SAVEPOINT
+		SCHEMA SELECT SET SOME TABLE THEN TO TRUE TRUNCATE UNION UNIQUE UPDATE
+		USING VALUES VIEW WHEN WHERE WINDOW WITH`) {
+		sqlKeywords[keyword] = true
+	}
+}
+
+func isSQLKeyword(word string) bool {
+	return sqlKeywords[strings.ToUpper(word)]
+}
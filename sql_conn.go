@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sqlDialect holds what differs between the drivers built on database/sql.
+type sqlDialect interface {
+	// valueKind tells how values of a declared column type are printed
+	valueKind(column *sql.ColumnType) ValueKind
+	// formatValue prints a scanned value as text
+	formatValue(column *sql.ColumnType, value any) string
+	// changes is the query that counts the rows the last statement changed
+	changes() string
+	// changeCount reads the rows changed from the result of a statement, for
+	// databases that answer statements with a count instead of no rows
+	changeCount(statement string, result ResultSet) (int64, bool)
+}
+
+// sqlConn runs statements on a single pinned connection so transactions
+// carry over between statements.
+type sqlConn struct {
+	db      *sql.DB
+	conn    *sql.Conn
+	dialect sqlDialect
+
+	// mu guards the fields below, which Cancel and the view read while a
+	// statement runs
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	closed   bool
+	txStatus TxStatus
+}
+
+// openSQLConn pins a connection of the pool, closing the pool when it fails.
+func openSQLConn(db *sql.DB, dialect sqlDialect) (*sqlConn, error) {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqlConn{db: db, conn: conn, dialect: dialect, txStatus: TX_IDLE}, nil
+}
+
+// strings reads the first column of every row.
+func (c *sqlConn) strings(query string, args ...any) ([]string, error) {
+	rows, err := c.conn.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// columns reads the name, type and nullability of columns.
+func (c *sqlConn) columns(query string, args ...any) ([]Column, error) {
+	rows, err := c.conn.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		if err := rows.Scan(&column.Name, &column.DataType, &column.Nullable); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// start derives a context Cancel can interrupt for the next statement.
+func (c *sqlConn) start(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		c.cancel = nil
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// running reports whether a statement is running.
+func (c *sqlConn) running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cancel != nil
+}
+
+// lost closes the connection when a failed statement left it unusable, such
+// as when the driver dropped it, so the next statement reconnects. Whatever
+// transaction it had is gone with it.
+func (c *sqlConn) lost(err error) {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		c.conn.PingContext(context.Background()) != nil {
+		c.Close()
+		c.setTxStatus(TX_IDLE)
+	}
+}
+
+// rows runs a statement and reads its rows as text with format. Statements
+// without columns are reported with their verb and the number of rows changed.
+func (c *sqlConn) rows(ctx context.Context, format func(*sql.ColumnType, any) string, statement string, args ...any) (ResultSet, int64, error) {
+	ctx, done := c.start(ctx)
+	defer done()
+
+	rows, err := c.conn.QueryContext(ctx, statement, args...)
+	if err != nil {
+		c.lost(err)
+		return ResultSet{}, 0, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return ResultSet{}, 0, err
+	}
+
+	var result ResultSet
+	numeric := make([]bool, len(columnTypes))
+	for i, column := range columnTypes {
+		result.fields = append(result.fields, column.Name())
+		result.kinds = append(result.kinds, c.dialect.valueKind(column))
+		numeric[i] = true
+	}
+
+	for rows.Next() {
+		raw := make([]any, len(columnTypes))
+		scan := make([]any, len(columnTypes))
+		for i := range raw {
+			scan[i] = &raw[i]
+		}
+		if err := rows.Scan(scan...); err != nil {
+			return ResultSet{}, 0, err
+		}
+
+		values := make([]*string, len(raw))
+		for i, value := range raw {
+			if value == nil {
+				continue
+			}
+
+			switch value.(type) {
+			case int64, float64:
+			default:
+				numeric[i] = false
+			}
+
+			text := format(columnTypes[i], value)
+			values[i] = &text
+		}
+		result.values = append(result.values, values)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		c.lost(err)
+		return ResultSet{}, 0, err
+	}
+
+	// Expressions have no declared type, their values tell whether they are numbers
+	for i, column := range columnTypes {
+		if column.DatabaseTypeName() == "" && numeric[i] && len(result.values) > 0 {
+			result.kinds[i] = VALUE_NUMBER
+		}
+	}
+
+	c.track(statement)
+
+	if changes, ok := c.dialect.changeCount(statement, result); ok {
+		result = ResultSet{command: fmt.Sprintf("%s %d", firstKeyword(statement), changes)}
+		return result, changes, nil
+	}
+
+	if len(result.fields) > 0 {
+		result.command = fmt.Sprintf("SELECT %d", len(result.values))
+		return result, int64(len(result.values)), nil
+	}
+
+	var changes int64
+	if err := c.conn.QueryRowContext(context.Background(), c.dialect.changes()).Scan(&changes); err != nil {
+		return ResultSet{}, 0, err
+	}
+	result.command = fmt.Sprintf("%s %d", firstKeyword(statement), changes)
+
+	return result, changes, nil
+}
+
+// formatSQLValue prints the values database/sql scans into an any.
+func formatSQLValue(value any) string {
+	switch value := value.(type) {
+	case []byte:
+		return string(value)
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// displayValue formats dates and timestamps for the grid as configured, other
+// values as the dialect prints them.
+func (c *sqlConn) displayValue(column *sql.ColumnType, value any) string {
+	if _, ok := value.(time.Time); ok && column.DatabaseTypeName() != "TIME" {
+		return displayValue(value, column.DatabaseTypeName() == "DATE")
+	}
+
+	return c.dialect.formatValue(column, value)
+}
+
+func (c *sqlConn) Query(ctx context.Context, sql string, args ...any) (Table, error) {
+	result, affected, err := c.rows(ctx, c.displayValue, sql, args...)
+	if err != nil {
+		return Table{}, err
+	}
+
+	table := Table{fields: result.fields, command: result.command, affected: affected}
+	for _, row := range result.values {
+		values := make([]string, len(row))
+		for i, value := range row {
+			values[i] = cfg.NullDisplay
+			if value != nil {
+				values[i] = *value
+			}
+		}
+		table.values = append(table.values, values)
+	}
+
+	return table, nil
+}
+
+func (c *sqlConn) QueryText(ctx context.Context, sql string) (ResultSet, error) {
+	result, _, err := c.rows(ctx, c.dialect.formatValue, sql)
+	return result, err
+}
+
+func (c *sqlConn) Exec(ctx context.Context, sql string) error {
+	ctx, done := c.start(ctx)
+	defer done()
+
+	if _, err := c.conn.ExecContext(ctx, sql); err != nil {
+		c.lost(err)
+		return err
+	}
+
+	c.track(sql)
+	return nil
+}
+
+// track follows the statements that begin and end transactions, as
+// database/sql does not report whether one is open. A failed statement does
+// not abort the transaction in these databases so it is never in the failed
+// state.
+func (c *sqlConn) track(sql string) {
+	tokens := significantTokens(sql)
+	if len(tokens) == 0 {
+		return
+	}
+
+	switch strings.ToUpper(tokens[0].text) {
+	case "BEGIN", "START", "SAVEPOINT":
+		c.setTxStatus(TX_ACTIVE)
+	case "COMMIT", "END":
+		c.setTxStatus(TX_IDLE)
+	case "ROLLBACK":
+		// ROLLBACK TO only undoes up to a savepoint
+		if len(tokens) == 1 || strings.ToUpper(tokens[1].text) != "TO" {
+			c.setTxStatus(TX_IDLE)
+		}
+	}
+}
+
+func (c *sqlConn) setTxStatus(status TxStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.txStatus = status
+}
+
+// Cancel interrupts the running statement through its context.
+func (c *sqlConn) Cancel() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	return nil
+}
+
+func (c *sqlConn) TxStatus() TxStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.txStatus
+}
+
+func (c *sqlConn) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+func (c *sqlConn) Close() {
+	c.conn.Close()
+	c.db.Close()
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+}
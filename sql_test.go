@@ -0,0 +1,144 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSQLRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"select 1",
+		"select 'it''s', E'a\\'b', \"quoted \"\" ident\" from t",
+		"select $body$ ; ' $inner$ $body$, $1, $$x$$",
+		"select a::int, arr[1:2], :name from t -- trailing ; comment",
+		"/* outer /* nested */ still comment */ select 1;",
+		"select 1.5e-3, .5, 10e, 'unterminated",
+		"select 'ünïcödé' || naïve from t",
+		"select `we``ird`, ? from t where a=? and b ?| c",
+	}
+
+	for _, input := range inputs {
+		var b strings.Builder
+		for _, token := range tokenizeSQL(input) {
+			b.WriteString(token.text)
+		}
+		if b.String() != input {
+			t.Errorf("tokens of %q join to %q", input, b.String())
+		}
+	}
+}
+
+func TestTokenizeSQLKinds(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []sqlToken
+	}{
+		{
+			sql: "select 'a;b'",
+			want: []sqlToken{
+				{TOKEN_WORD, "select"}, {TOKEN_WHITESPACE, " "}, {TOKEN_STRING, "'a;b'"},
+			},
+		},
+		{
+			sql: "E'it\\'s'",
+			want: []sqlToken{
+				{TOKEN_STRING, "E'it\\'s'"},
+			},
+		},
+		{
+			sql: "$fn$ select ; $fn$",
+			want: []sqlToken{
+				{TOKEN_STRING, "$fn$ select ; $fn$"},
+			},
+		},
+		{
+			sql: "$$;$$",
+			want: []sqlToken{
+				{TOKEN_STRING, "$$;$$"},
+			},
+		},
+		{
+			sql: "$12",
+			want: []sqlToken{
+				{TOKEN_PARAM, "$12"},
+			},
+		},
+		{
+			sql: "a::text",
+			want: []sqlToken{
+				{TOKEN_WORD, "a"}, {TOKEN_OPERATOR, "::"}, {TOKEN_WORD, "text"},
+			},
+		},
+		{
+			sql: "= :id",
+			want: []sqlToken{
+				{TOKEN_OPERATOR, "="}, {TOKEN_WHITESPACE, " "}, {TOKEN_PARAM, ":id"},
+			},
+		},
+		{
+			sql: "arr[lo:hi]",
+			want: []sqlToken{
+				{TOKEN_WORD, "arr"}, {TOKEN_OPERATOR, "["}, {TOKEN_WORD, "lo"},
+				{TOKEN_OPERATOR, ":"}, {TOKEN_WORD, "hi"}, {TOKEN_OPERATOR, "]"},
+			},
+		},
+		{
+			sql: "/* a /* b */ c */x",
+			want: []sqlToken{
+				{TOKEN_COMMENT, "/* a /* b */ c */"}, {TOKEN_WORD, "x"},
+			},
+		},
+		{
+			sql: "1+-- comment",
+			want: []sqlToken{
+				{TOKEN_NUMBER, "1"}, {TOKEN_OPERATOR, "+"}, {TOKEN_COMMENT, "-- comment"},
+			},
+		},
+		{
+			sql: "\"a\"\"b\".c",
+			want: []sqlToken{
+				{TOKEN_QUOTED_IDENT, "\"a\"\"b\""}, {TOKEN_OPERATOR, "."}, {TOKEN_WORD, "c"},
+			},
+		},
+		{
+			sql: "`a``b`.c",
+			want: []sqlToken{
+				{TOKEN_QUOTED_IDENT, "`a``b`"}, {TOKEN_OPERATOR, "."}, {TOKEN_WORD, "c"},
+			},
+		},
+		{
+			sql: "id=?",
+			want: []sqlToken{
+				{TOKEN_WORD, "id"}, {TOKEN_OPERATOR, "="}, {TOKEN_PARAM, "?"},
+			},
+		},
+		{
+			sql: "a ?| b",
+			want: []sqlToken{
+				{TOKEN_WORD, "a"}, {TOKEN_WHITESPACE, " "}, {TOKEN_OPERATOR, "?|"},
+				{TOKEN_WHITESPACE, " "}, {TOKEN_WORD, "b"},
+			},
+		},
+		{
+			sql: "(?,?)",
+			want: []sqlToken{
+				{TOKEN_OPERATOR, "("}, {TOKEN_PARAM, "?"}, {TOKEN_OPERATOR, ","},
+				{TOKEN_PARAM, "?"}, {TOKEN_OPERATOR, ")"},
+			},
+		},
+		{
+			sql: "3.14e+2x",
+			want: []sqlToken{
+				{TOKEN_NUMBER, "3.14e+2"}, {TOKEN_WORD, "x"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if got := tokenizeSQL(test.sql); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tokenizeSQL(%q)\n got %v\nwant %v", test.sql, got, test.want)
+		}
+	}
+}
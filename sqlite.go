@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+type sqliteDriver struct{}
+
+// Connect opens the database file of the connection, which has to exist so a
+// mistyped path does not leave an empty database behind.
+func (sqliteDriver) Connect(params Connection) (DriverConn, error) {
+	path := expandHome(params.Database)
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+
+	mode := "rw"
+	if params.ReadOnly {
+		mode = "ro"
+	}
+
+	query := url.Values{}
+	query.Set("mode", mode)
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + query.Encode()
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+
+	conn, err := openSQLConn(db, sqliteDialect{})
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+
+	return &sqliteConn{conn}, nil
+}
+
+type sqliteConn struct {
+	*sqlConn
+}
+
+func (c *sqliteConn) Tables() ([]string, error) {
+	return c.strings(`SELECT name FROM sqlite_schema
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		ORDER BY name`)
+}
+
+func (c *sqliteConn) Columns(table string) ([]Column, error) {
+	return c.columns(`SELECT name, type, "notnull" = 0 FROM pragma_table_info(?) ORDER BY cid`, table)
+}
+
+// TableDDL returns the statements sqlite keeps for the table and its indexes.
+func (c *sqliteConn) TableDDL(table string) (string, error) {
+	statements, err := c.strings(`SELECT sql || ';' FROM sqlite_schema
+		WHERE tbl_name = ? AND sql IS NOT NULL
+		ORDER BY type = 'index', name`, table)
+	if err != nil {
+		return "", err
+	}
+
+	if len(statements) == 0 {
+		return "", fmt.Errorf("no table named %s", table)
+	}
+
+	return strings.Join(statements, "\n\n"), nil
+}
+
+type sqliteDialect struct{}
+
+// valueKind follows the rules sqlite uses to give a declared type its
+// affinity.
+func (sqliteDialect) valueKind(column *sql.ColumnType) ValueKind {
+	declared := strings.ToUpper(column.DatabaseTypeName())
+
+	switch {
+	case strings.Contains(declared, "BOOL"):
+		return VALUE_BOOL
+	case strings.Contains(declared, "JSON"):
+		return VALUE_JSON
+	case strings.Contains(declared, "INT"),
+		strings.Contains(declared, "REAL"),
+		strings.Contains(declared, "FLOA"),
+		strings.Contains(declared, "DOUB"),
+		strings.Contains(declared, "NUMERIC"),
+		strings.Contains(declared, "DECIMAL"):
+		return VALUE_NUMBER
+	default:
+		return VALUE_TEXT
+	}
+}
+
+func (sqliteDialect) formatValue(column *sql.ColumnType, value any) string {
+	return formatSQLValue(value)
+}
+
+func (sqliteDialect) changes() string {
+	return "SELECT changes()"
+}
+
+func (sqliteDialect) changeCount(statement string, result ResultSet) (int64, bool) {
+	return 0, false
+}
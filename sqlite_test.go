@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestSQLite connects to a new database file in a temporary directory.
+func openTestSQLite(t *testing.T) DriverConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sqliteDriver{}.Connect(Connection{Driver: SQLITE, Database: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+func TestSQLiteSchema(t *testing.T) {
+	conn := openTestSQLite(t)
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, score REAL)",
+		"CREATE INDEX users_name ON users (name)",
+		"CREATE VIEW names AS SELECT name FROM users",
+	} {
+		if err := conn.Exec(ctx, sql); err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+	}
+
+	tables, err := conn.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"names", "users"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("Tables() = %v, want %v", tables, want)
+	}
+
+	columns, err := conn.Columns("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Column{
+		{Name: "id", DataType: "INTEGER", Nullable: true},
+		{Name: "name", DataType: "TEXT", Nullable: false},
+		{Name: "score", DataType: "REAL", Nullable: true},
+	}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("Columns(users) = %+v, want %+v", columns, want)
+	}
+
+	ddl, err := conn.(ddlSource).TableDDL("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDDL := "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, score REAL);\n\n" +
+		"CREATE INDEX users_name ON users (name);"
+	if ddl != wantDDL {
+		t.Errorf("TableDDL(users) =\n%s\nwant\n%s", ddl, wantDDL)
+	}
+
+	if _, err := conn.(ddlSource).TableDDL("missing"); err == nil {
+		t.Error("TableDDL(missing) succeeded")
+	}
+}
+
+func TestSQLiteQuery(t *testing.T) {
+	conn := openTestSQLite(t)
+	ctx := context.Background()
+
+	if err := conn.Exec(ctx, "CREATE TABLE t (id INTEGER, note TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sql      string
+		command  string
+		affected int64
+		values   [][]string
+	}{
+		{sql: "INSERT INTO t VALUES (1, 'a'), (2, 'b'), (3, NULL)", command: "INSERT 3", affected: 3},
+		{sql: "UPDATE t SET note = 'c' WHERE id > 1", command: "UPDATE 2", affected: 2},
+		{sql: "DELETE FROM t WHERE id = 1", command: "DELETE 1", affected: 1},
+		{
+			sql:      "SELECT id, note, id * 2 FROM t ORDER BY id",
+			command:  "SELECT 2",
+			affected: 2,
+			values:   [][]string{{"2", "c", "4"}, {"3", "c", "6"}},
+		},
+	}
+
+	for _, test := range tests {
+		table, err := conn.Query(ctx, test.sql)
+		if err != nil {
+			t.Errorf("%s: %v", test.sql, err)
+			continue
+		}
+		if table.command != test.command || table.affected != test.affected || !reflect.DeepEqual(table.values, test.values) {
+			t.Errorf("%s: got %q, %d, %q, want %q, %d, %q", test.sql,
+				table.command, table.affected, table.values, test.command, test.affected, test.values)
+		}
+	}
+}
+
+func TestSQLiteTxStatus(t *testing.T) {
+	conn := openTestSQLite(t)
+	ctx := context.Background()
+
+	steps := []struct {
+		sql  string
+		want TxStatus
+	}{
+		{sql: "CREATE TABLE t (id INTEGER)", want: TX_IDLE},
+		{sql: "BEGIN", want: TX_ACTIVE},
+		{sql: "INSERT INTO t VALUES (1)", want: TX_ACTIVE},
+		{sql: "SAVEPOINT s", want: TX_ACTIVE},
+		{sql: "ROLLBACK TO s", want: TX_ACTIVE},
+		{sql: "COMMIT", want: TX_IDLE},
+		{sql: "BEGIN TRANSACTION", want: TX_ACTIVE},
+		{sql: "ROLLBACK", want: TX_IDLE},
+		{sql: "BEGIN", want: TX_ACTIVE},
+		{sql: "END", want: TX_IDLE},
+	}
+
+	for _, step := range steps {
+		if _, err := conn.Query(ctx, step.sql); err != nil {
+			t.Fatalf("%s: %v", step.sql, err)
+		}
+		if got := conn.TxStatus(); got != step.want {
+			t.Errorf("after %s: TxStatus() = %v, want %v", step.sql, got, step.want)
+		}
+	}
+
+	// A failed statement leaves the transaction open
+	if err := conn.Exec(ctx, "BEGIN"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Exec(ctx, "INSERT INTO missing VALUES (1)"); err == nil {
+		t.Fatal("insert into a missing table succeeded")
+	}
+	if got := conn.TxStatus(); got != TX_ACTIVE {
+		t.Errorf("after a failed statement: TxStatus() = %v, want %v", got, TX_ACTIVE)
+	}
+}
+
+// TestSQLiteTxStatusWhileRunning reads the status as the view does while
+// statements run, for the race detector to check.
+func TestSQLiteTxStatusWhileRunning(t *testing.T) {
+	conn := openTestSQLite(t)
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			conn.TxStatus()
+			conn.Closed()
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		for _, sql := range []string{"BEGIN", "SELECT 1", "COMMIT"} {
+			if _, err := conn.Query(ctx, sql); err != nil {
+				t.Fatalf("%s: %v", sql, err)
+			}
+		}
+	}
+	<-done
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds the colours of the interface as ansi numbers or #hex.
+type Theme struct {
+	// Focused text and borders
+	Text string `toml:"text"`
+	// Help, unfocused items and borders
+	Muted string `toml:"muted"`
+	// Focused inputs and the cursor
+	Accent string `toml:"accent"`
+	// Selected items and tables
+	Selected string `toml:"selected"`
+	Success  string `toml:"success"`
+	Error    string `toml:"error"`
+	// Open transactions, numbers in SQL
+	Warning string `toml:"warning"`
+	// Read-only connections, keywords in SQL
+	Info string `toml:"info"`
+	// Selected row of the result grid
+	RowForeground string `toml:"row_foreground"`
+	RowBackground string `toml:"row_background"`
+}
+
+func defaultTheme() Theme {
+	return Theme{
+		Text:          WHITE,
+		Muted:         GREY,
+		Accent:        RED,
+		Selected:      MAGENTA,
+		Success:       GREEN,
+		Error:         RED,
+		Warning:       YELLOW,
+		Info:          BLUE,
+		RowForeground: "229",
+		RowBackground: "57",
+	}
+}
+
+var colorPattern = regexp.MustCompile(`^([0-9]{1,3}|#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3})$`)
+
+// validate puts back the default of each colour that is not an ansi number
+// or #hex.
+func (t *Theme) validate(defaults Theme) []string {
+	colors := []struct {
+		name     string
+		value    *string
+		fallback string
+	}{
+		{"text", &t.Text, defaults.Text},
+		{"muted", &t.Muted, defaults.Muted},
+		{"accent", &t.Accent, defaults.Accent},
+		{"selected", &t.Selected, defaults.Selected},
+		{"success", &t.Success, defaults.Success},
+		{"error", &t.Error, defaults.Error},
+		{"warning", &t.Warning, defaults.Warning},
+		{"info", &t.Info, defaults.Info},
+		{"row_foreground", &t.RowForeground, defaults.RowForeground},
+		{"row_background", &t.RowBackground, defaults.RowBackground},
+	}
+
+	var problems []string
+	for _, color := range colors {
+		if !colorPattern.MatchString(*color.value) {
+			problems = append(problems, fmt.Sprintf("theme.%s must be an ansi number or #hex colour, not %q", color.name, *color.value))
+			*color.value = color.fallback
+		}
+	}
+
+	return problems
+}
+
+// apply sets the styles of every view to the colours of the theme.
+func (t Theme) apply() {
+	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color(t.Selected))
+	focusedItemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Accent))
+	focusedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Text))
+	blurredStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Muted))
+	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Success))
+	errorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Error))
+	helpStyle = blurredStyle.Copy().PaddingLeft(2)
+	cursorStyle = focusedItemStyle.Copy()
+	overlayStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(t.Accent)).
+		Padding(0, 1)
+
+	modelStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color(t.Muted))
+	focusedModelStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color(t.Text))
+	blurredModelSideBarStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Muted))
+	selectedTableStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Selected))
+	activeTxStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color(t.Warning))
+	readOnlyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Text)).Background(lipgloss.Color(t.Info))
+	failedTxStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Text)).Background(lipgloss.Color(t.Error))
+
+	keywordStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Info)).Bold(true)
+	identifierStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Text))
+	stringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Success))
+	numberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Warning))
+	commentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Muted)).Italic(true)
+	placeholderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(t.Selected))
+
+	focusedButton = focusedStyle.Copy().Render("[ Submit ]")
+	blurredButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Submit"))
+	focusedTestButton = focusedStyle.Copy().Render("[ Test ]")
+	blurredTestButton = fmt.Sprintf("[ %s ]", blurredStyle.Render("Test"))
+	errorTestButton = fmt.Sprintf("[ %s ]", errorStyle.Render("Test"))
+	successTestButton = fmt.Sprintf("[ %s ]", successStyle.Render("Test"))
+}
+
+func init() {
+	cfg.Theme.apply()
+}
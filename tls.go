@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// validateSSL checks the TLS settings before connecting so a typo is not
+// reported as a failed handshake.
+func (params Connection) validateSSL() error {
+	if params.SSLMode != "" && !slices.Contains(sslModes, params.SSLMode) {
+		return fmt.Errorf("sslmode must be one of %s", strings.Join(sslModes, ", "))
+	}
+
+	if params.TLS != "" && !slices.Contains(mysqlTLSModes, params.TLS) {
+		return fmt.Errorf("tls must be one of %s", strings.Join(mysqlTLSModes, ", "))
+	}
+
+	if (params.SSLCert == "") != (params.SSLKey == "") {
+		return errors.New("client certificate and key must be set together")
+	}
+
+	files := []struct {
+		name string
+		path string
+	}{
+		{"root CA", params.SSLRootCert},
+		{"client certificate", params.SSLCert},
+		{"client key", params.SSLKey},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(expandHome(file.path)); err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+
+	return nil
+}
+
+// describeConnectError explains TLS negotiation failures, which pgx reports
+// wrapped in generic connection errors.
+func describeConnectError(err error) error {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var header tls.RecordHeaderError
+	var alert tls.AlertError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("TLS: server certificate is signed by an unknown authority, set the root CA: %w", err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("TLS: server certificate is not valid for host %s, use verify-ca or connect by a name it covers: %w", hostname.Host, err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("TLS: server certificate is invalid: %w", err)
+	case errors.As(err, &header):
+		return fmt.Errorf("TLS: server did not answer with TLS: %w", err)
+	case errors.As(err, &alert):
+		return fmt.Errorf("TLS: server rejected the handshake, check the client certificate and key: %w", err)
+	case strings.Contains(err.Error(), "server refused TLS connection"):
+		return fmt.Errorf("TLS: server does not accept TLS connections, use sslmode disable or prefer: %w", err)
+	}
+
+	return err
+}
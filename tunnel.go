@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Tunnel forwards a local port to the database through an SSH bastion.
+type Tunnel struct {
+	client   *ssh.Client
+	listener net.Listener
+	remote   string
+	// Connection to ssh-agent when its keys are used
+	agent net.Conn
+}
+
+// errNoTunnel refuses connections to a database behind a bastion while the
+// tunnel is not open, rather than trying the database host directly.
+var errNoTunnel = errors.New("ssh tunnel is not open, not connecting to the database directly")
+
+// checkTunnel returns errNoTunnel when the connection goes through an SSH
+// bastion but has no tunnel to connect through.
+func (params Connection) checkTunnel() error {
+	if params.server() && params.SSHHost != "" && params.tunnelAddr == "" {
+		return errNoTunnel
+	}
+
+	return nil
+}
+
+// sshAuth uses the key file of the connection, or the keys of the running
+// ssh-agent without one. The connection to the agent is returned for the
+// caller to close.
+func (params Connection) sshAuth() (ssh.AuthMethod, net.Conn, error) {
+	if params.SSHKeyFile != "" {
+		key, err := os.ReadFile(expandHome(params.SSHKeyFile))
+		if err != nil {
+			return nil, nil, fmt.Errorf("ssh key: %w", err)
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		var passphrase *ssh.PassphraseMissingError
+		if errors.As(err, &passphrase) {
+			return nil, nil, errors.New("ssh key is protected by a passphrase, add it to ssh-agent and leave the key file empty")
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ssh key: %w", err)
+		}
+
+		return ssh.PublicKeys(signer), nil, nil
+	}
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, errors.New("no ssh key file set and SSH_AUTH_SOCK is empty")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ssh-agent: %w", err)
+	}
+
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), conn, nil
+}
+
+func (params Connection) sshHostKeyCallback() (ssh.HostKeyCallback, error) {
+	path := params.SSHKnownHosts
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(homeDir, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("known_hosts: %w", err)
+	}
+
+	return callback, nil
+}
+
+// OpenTunnel connects to the bastion and listens on a free local port,
+// forwarding every connection to the database host.
+func (params Connection) OpenTunnel() (*Tunnel, error) {
+	// The database is reached from the bastion, at its default port unless set
+	params = params.withDefaults()
+
+	auth, agentConn, err := params.sshAuth()
+	if err != nil {
+		return nil, err
+	}
+	closeAgent := func() {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+	}
+
+	hostKeyCallback, err := params.sshHostKeyCallback()
+	if err != nil {
+		closeAgent()
+		return nil, err
+	}
+
+	port := params.SSHPort
+	if port == "" {
+		port = "22"
+	}
+
+	user := params.SSHUser
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(params.SSHHost, port), &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		closeAgent()
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return nil, fmt.Errorf("ssh: %s is not in known_hosts, connect once with ssh to add it", params.SSHHost)
+		}
+		return nil, fmt.Errorf("ssh: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		client.Close()
+		closeAgent()
+		return nil, err
+	}
+
+	t := &Tunnel{
+		client:   client,
+		listener: listener,
+		remote:   net.JoinHostPort(params.Host, params.Port),
+		agent:    agentConn,
+	}
+	go t.serve()
+
+	return t, nil
+}
+
+// Addr returns the local address forwarded to the database.
+func (t *Tunnel) Addr() string {
+	return t.listener.Addr().String()
+}
+
+func (t *Tunnel) serve() {
+	for {
+		local, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		go t.forward(local)
+	}
+}
+
+func (t *Tunnel) forward(local net.Conn) {
+	remote, err := t.client.Dial("tcp", t.remote)
+	if err != nil {
+		local.Close()
+		return
+	}
+
+	go func() {
+		io.Copy(remote, local)
+		remote.Close()
+	}()
+
+	io.Copy(local, remote)
+	local.Close()
+}
+
+func (t *Tunnel) Close() {
+	if t == nil {
+		return
+	}
+
+	t.listener.Close()
+	t.client.Close()
+	if t.agent != nil {
+		t.agent.Close()
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshServer is an in-process bastion that only forwards direct-tcpip
+// channels, for connections authenticated with clientKey.
+type sshServer struct {
+	addr    string
+	hostKey ssh.Signer
+}
+
+func newSigner(t *testing.T) (ed25519.PrivateKey, ssh.Signer) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, signer
+}
+
+func startSSHServer(t *testing.T, clientKey ssh.PublicKey) sshServer {
+	t.Helper()
+
+	_, hostKey := newSigner(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config)
+		}
+	}()
+
+	return sshServer{addr: listener.Addr().String(), hostKey: hostKey}
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip")
+			continue
+		}
+
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+
+		go func() {
+			io.Copy(channel, remote)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(remote, channel)
+			remote.Close()
+		}()
+	}
+}
+
+// startEchoServer stands in for the database behind the bastion.
+func startEchoServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port
+}
+
+func writeKnownHosts(t *testing.T, dir string, addr string, key ssh.PublicKey) string {
+	t.Helper()
+
+	path := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, key) + "\n"
+	if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func tunnelConnection(t *testing.T, server sshServer, knownHosts string, keyFile string) Connection {
+	t.Helper()
+
+	dbHost, dbPort := startEchoServer(t)
+	sshHost, sshPort, _ := net.SplitHostPort(server.addr)
+
+	return Connection{
+		Host:          dbHost,
+		Port:          dbPort,
+		SSHHost:       sshHost,
+		SSHPort:       sshPort,
+		SSHUser:       "termtable",
+		SSHKeyFile:    keyFile,
+		SSHKnownHosts: knownHosts,
+	}
+}
+
+func echoThrough(t *testing.T, addr string) {
+	t.Helper()
+
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "ping" {
+		t.Fatalf("reply = %q, want ping", reply)
+	}
+}
+
+func TestTunnelForward(t *testing.T) {
+	dir := t.TempDir()
+	clientKey, clientSigner := newSigner(t)
+	server := startSSHServer(t, clientSigner.PublicKey())
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := tunnelConnection(t, server, writeKnownHosts(t, dir, server.addr, server.hostKey.PublicKey()), keyFile)
+
+	tunnel, err := conn.OpenTunnel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Several connections share the tunnel
+	echoThrough(t, tunnel.Addr())
+	echoThrough(t, tunnel.Addr())
+
+	tunnel.Close()
+	if c, err := net.DialTimeout("tcp", tunnel.Addr(), time.Second); err == nil {
+		c.Close()
+		t.Error("tunnel still accepts connections after Close")
+	}
+}
+
+func TestTunnelDefaultPort(t *testing.T) {
+	dir := t.TempDir()
+	clientKey, clientSigner := newSigner(t)
+	server := startSSHServer(t, clientSigner.PublicKey())
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	knownHosts := writeKnownHosts(t, dir, server.addr, server.hostKey.PublicKey())
+
+	tests := []struct {
+		driver DriverKind
+		host   string
+		want   string
+	}{
+		{driver: POSTGRES, host: "db", want: "db:5432"},
+		{driver: MYSQL, host: "db", want: "db:3306"},
+		{driver: POSTGRES, want: "localhost:5432"},
+	}
+
+	for _, test := range tests {
+		conn := tunnelConnection(t, server, knownHosts, keyFile)
+		conn.Driver, conn.Host, conn.Port = test.driver, test.host, ""
+
+		tunnel, err := conn.OpenTunnel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tunnel.Close()
+
+		if tunnel.remote != test.want {
+			t.Errorf("%s %q: remote = %q, want %q", test.driver, test.host, tunnel.remote, test.want)
+		}
+	}
+}
+
+func TestTunnelAgent(t *testing.T) {
+	dir := t.TempDir()
+	clientKey, clientSigner := newSigner(t)
+	server := startSSHServer(t, clientSigner.PublicKey())
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: clientKey}); err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	served := make(chan struct{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		agent.ServeAgent(keyring, conn)
+		close(served)
+	}()
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	conn := tunnelConnection(t, server, writeKnownHosts(t, dir, server.addr, server.hostKey.PublicKey()), "")
+
+	tunnel, err := conn.OpenTunnel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoThrough(t, tunnel.Addr())
+
+	// Closing the tunnel closes its connection to the agent
+	tunnel.Close()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Error("agent connection left open after Close")
+	}
+}
+
+func TestTunnelKnownHosts(t *testing.T) {
+	dir := t.TempDir()
+	clientKey, clientSigner := newSigner(t)
+	server := startSSHServer(t, clientSigner.PublicKey())
+
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, otherKey := newSigner(t)
+	tests := []struct {
+		name       string
+		knownHosts string
+		want       string
+	}{
+		{
+			name:       "changed key",
+			knownHosts: writeKnownHosts(t, t.TempDir(), server.addr, otherKey.PublicKey()),
+			want:       "knownhosts: key mismatch",
+		},
+		{
+			name:       "unknown host",
+			knownHosts: writeKnownHosts(t, t.TempDir(), "192.0.2.1:22", server.hostKey.PublicKey()),
+			want:       "is not in known_hosts",
+		},
+	}
+
+	for _, test := range tests {
+		conn := tunnelConnection(t, server, test.knownHosts, keyFile)
+		tunnel, err := conn.OpenTunnel()
+		if err == nil {
+			tunnel.Close()
+			t.Errorf("%s: tunnel opened, want it refused", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: err = %v, want it to contain %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestCheckTunnel(t *testing.T) {
+	conn := Connection{Host: "db", Port: "5432", SSHHost: "bastion"}
+	if err := conn.checkTunnel(); !errors.Is(err, errNoTunnel) {
+		t.Errorf("checkTunnel() = %v without a tunnel, want errNoTunnel", err)
+	}
+	if _, err := conn.Connect(); !errors.Is(err, errNoTunnel) {
+		t.Errorf("Connect() = %v without a tunnel, want errNoTunnel", err)
+	}
+	if _, err := (mysqlDriver{}).Connect(Connection{Driver: MYSQL, SSHHost: "bastion"}); !errors.Is(err, errNoTunnel) {
+		t.Errorf("mysql Connect() = %v without a tunnel, want errNoTunnel", err)
+	}
+
+	conn.tunnelAddr = "127.0.0.1:1"
+	if err := conn.checkTunnel(); err != nil {
+		t.Errorf("checkTunnel() = %v with a tunnel", err)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// table lays the file out as a result so it can be shown in the grid. NULL
+// values are shown empty.
+func (f importFile) table() Table {
+	data := Table{fields: f.header}
+	for _, record := range f.records {
+		values := make([]string, len(record))
+		for i, value := range record {
+			if value != nil {
+				values[i] = fmt.Sprint(value)
+			}
+		}
+		data.values = append(data.values, values)
+	}
+
+	return data
+}
+
+// readViewData reads a csv or json file, or stdin for "-". The format comes
+// from the extension, or for stdin from whether the data starts like json.
+func readViewData(path string, format string) (importFile, error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(expandHome(path))
+		if err != nil {
+			return importFile{}, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json", ".ndjson", ".jsonl":
+			format = "json"
+		case ".csv":
+			format = "csv"
+		default:
+			buffered := bufio.NewReader(r)
+			start, _ := buffered.Peek(512)
+			start = bytes.TrimSpace(start)
+			if bytes.HasPrefix(start, []byte("{")) || bytes.HasPrefix(start, []byte("[")) {
+				format = "json"
+			} else {
+				format = "csv"
+			}
+			r = buffered
+		}
+	}
+
+	switch format {
+	case "json":
+		return readNDJSON(r)
+	case "csv":
+		return readCSV(r)
+	default:
+		return importFile{}, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// ViewerModel shows a file in the grid, on its own without a connection.
+type ViewerModel struct {
+	name string
+	grid GridModel
+}
+
+func (m ViewerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ViewerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.grid, cmd = m.grid.Update(msg)
+	if m.grid.back {
+		return m, tea.Quit
+	}
+
+	return m, cmd
+}
+
+func (m ViewerModel) View() string {
+	return quitTextStyle.Render(titleStyle.Render(m.name) + "\n\n" + m.grid.View())
+}
+
+func viewCommand(args []string) int {
+	fs := newFlagSet("view")
+	format := fs.String("format", "", "csv or json, by default from the extension or the data")
+
+	help := func(w io.Writer) {
+		fmt.Fprintln(w, "Usage: termtable view [flags] <file>")
+		fmt.Fprintln(w, "       cat data.json | termtable view -")
+		fmt.Fprintln(w)
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+		fs.SetOutput(os.Stderr)
+	}
+
+	paths, err := parseArgs(fs, args)
+	if err != nil {
+		return flagError(err, help)
+	}
+	if len(paths) != 1 {
+		help(os.Stderr)
+		return EXIT_USAGE
+	}
+
+	file, err := readViewData(paths[0], *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "termtable:", err)
+		return EXIT_ERROR
+	}
+
+	name := paths[0]
+	if name == "-" {
+		name = "stdin"
+	}
+
+	m := ViewerModel{name: name, grid: NewGridModel(file.table())}
+
+	// Keys are read from the terminal when the data came through stdin
+	if _, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithInputTTY()).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error running program:", err)
+		return EXIT_ERROR
+	}
+
+	return EXIT_OK
+}